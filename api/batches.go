@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/artnikel/taskscheduler/scheduler"
+)
+
+// maxBatchSize limits the number of tasks in one batch
+const maxBatchSize = 1000
+
+// CreateBatch handles POST requests to submit several tasks of any type at once
+func (h *Handler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.Logger.Error.Println("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Tasks []taskSpec `json:"tasks"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Tasks) == 0 {
+		h.Logger.Error.Println("invalid request body:", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Tasks) > maxBatchSize {
+		h.Logger.Error.Println("batch too large:", len(req.Tasks))
+		http.Error(w, fmt.Sprintf("batch too large, at most %d tasks", maxBatchSize), http.StatusBadRequest)
+		return
+	}
+	items := make([]scheduler.BatchItem, 0, len(req.Tasks))
+	for i := range req.Tasks {
+		fn, opts, err := req.Tasks[i].build()
+		if err != nil {
+			h.Logger.Error.Println("invalid task spec in batch:", err)
+			http.Error(w, fmt.Sprintf("invalid task spec at index %d: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		items = append(items, scheduler.BatchItem{Task: fn, Options: opts})
+	}
+	batchID, ids, err := h.Scheduler.AddBatch(items)
+	if err != nil {
+		h.submitError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"batch_id": batchID, "task_ids": ids})
+}
+
+// GetBatch handles GET requests to retrieve the progress of a batch
+func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.Logger.Error.Println("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/batches/")
+	if id == "" {
+		h.Logger.Error.Println("missing batch ID in request")
+		http.Error(w, "missing batch ID", http.StatusBadRequest)
+		return
+	}
+	batch, err := h.Scheduler.GetBatch(id)
+	if errors.Is(err, scheduler.ErrBatchNotFound) {
+		h.Logger.Error.Println("batch not found for ID:", id)
+		http.Error(w, "batch not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.Logger.Error.Println("failed to get batch", id, ":", err)
+		http.Error(w, "failed to get batch", http.StatusInternalServerError)
+		return
+	}
+	counts := make(map[string]int, len(batch.Counts))
+	for status, n := range batch.Counts {
+		counts[string(status)] = n
+	}
+	items := make([]map[string]interface{}, 0, len(batch.Tasks))
+	for _, task := range batch.Tasks {
+		items = append(items, taskResponse(task))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"batch_id": batch.ID,
+		"total":    len(batch.Tasks),
+		"finished": batch.Finished,
+		"progress": float64(batch.Finished) / float64(len(batch.Tasks)),
+		"counts":   counts,
+		"tasks":    items,
+	})
+}
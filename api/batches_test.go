@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/scheduler"
+)
+
+func TestCreateBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	s := scheduler.NewScheduler(2)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"tasks": []map[string]interface{}{
+			{"type": "http_status", "url": server.URL},
+			{"type": "http_status", "url": server.URL, "priority": 9, "tags": []string{"deploy"}},
+		},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/tasks/batch", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.CreateBatch(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var created struct {
+		BatchID string   `json:"batch_id"`
+		TaskIDs []string `json:"task_ids"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&created)
+	if created.BatchID == "" || len(created.TaskIDs) != 2 {
+		t.Fatalf("unexpected response: %+v", created)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	req = httptest.NewRequest(http.MethodGet, "/batches/"+created.BatchID, http.NoBody)
+	w = httptest.NewRecorder()
+	h.GetBatch(w, req)
+
+	resp = w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var progress struct {
+		Total    int            `json:"total"`
+		Finished int            `json:"finished"`
+		Progress float64        `json:"progress"`
+		Counts   map[string]int `json:"counts"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&progress)
+	if progress.Total != 2 || progress.Finished != 2 || progress.Progress != 1 || progress.Counts["done"] != 2 {
+		t.Errorf("unexpected progress: %+v", progress)
+	}
+}
+
+func TestCreateBatch_Invalid(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+
+	for _, body := range []string{
+		`{"tasks": []}`,
+		`{"tasks": [{"type": "ping", "address": "example.com"}, {"type": "ping"}]}`,
+		`{"tasks": [{"type": "unknown"}]}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/tasks/batch", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		h.CreateBatch(w, req)
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, w.Result().StatusCode)
+		}
+	}
+	if stats := s.GetStats(); stats["pending"]+stats["running"]+stats["done"]+stats["failed"] != 0 {
+		t.Errorf("expected no task to be queued from invalid batches, got %v", stats)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/batches/missing", http.NoBody)
+	w := httptest.NewRecorder()
+	h.GetBatch(w, req)
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Result().StatusCode)
+	}
+}
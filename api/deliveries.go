@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/artnikel/taskscheduler/models"
+)
+
+// GetDeliveries handles GET requests to list the callback delivery attempts of a task
+func (h *Handler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.Logger.Error.Println("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/tasks/"), "/deliveries")
+	if id == "" || strings.Contains(id, "/") {
+		h.Logger.Error.Println("missing task ID in request")
+		http.Error(w, "missing task ID", http.StatusBadRequest)
+		return
+	}
+	task, ok := h.Scheduler.GetTask(id)
+	if !ok {
+		h.Logger.Error.Println("task not found for ID:", id)
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	deliveries := task.Deliveries
+	if deliveries == nil {
+		deliveries = []models.Delivery{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"task_id":      task.ID,
+		"callback_url": task.CallbackURL,
+		"deliveries":   deliveries,
+	})
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/scheduler"
+)
+
+func TestGetDeliveries(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "ok"}, nil
+	}, scheduler.TaskOptions{CallbackURL: "https://hooks.example.com"})
+	time.Sleep(20 * time.Millisecond)
+	_ = s.RecordDelivery(id, models.Delivery{Attempt: 1, Event: "succeeded", URL: "https://hooks.example.com", StatusCode: http.StatusServiceUnavailable})
+	_ = s.RecordDelivery(id, models.Delivery{Attempt: 2, Event: "succeeded", URL: "https://hooks.example.com", StatusCode: http.StatusOK})
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks/"+id+"/deliveries", http.NoBody)
+	w := httptest.NewRecorder()
+	h.HandleTask(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var data struct {
+		TaskID     string            `json:"task_id"`
+		Deliveries []models.Delivery `json:"deliveries"`
+	}
+	_ = json.NewDecoder(w.Body).Decode(&data)
+	if data.TaskID != id || len(data.Deliveries) != 2 || data.Deliveries[0].StatusCode != http.StatusServiceUnavailable || data.Deliveries[1].StatusCode != http.StatusOK {
+		t.Errorf("unexpected deliveries: %+v", data)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/tasks/missing/deliveries", http.NoBody)
+	w = httptest.NewRecorder()
+	h.HandleTask(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestCreatePingTask_InvalidCallbackURL(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+
+	for _, callback := range []string{"hooks.example.com", "ftp://hooks.example.com", "http://"} {
+		body, _ := json.Marshal(map[string]string{"address": "example.com", "callback_url": callback})
+		req := httptest.NewRequest(http.MethodPost, "/tasks/ping", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+		h.CreatePingTask(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %q, got %d", callback, w.Code)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/scheduler"
+)
+
+// eventFilter selects the events sent on a stream, empty fields match every event
+type eventFilter struct {
+	TaskID string
+	Type   constants.TaskType
+	Tag    string
+}
+
+// parseEventFilter reads the task_id, type and tag query parameters
+func parseEventFilter(query url.Values) eventFilter {
+	return eventFilter{
+		TaskID: query.Get("task_id"),
+		Type:   constants.TaskType(query.Get("type")),
+		Tag:    query.Get("tag"),
+	}
+}
+
+// match reports whether an event passes the filter
+func (f eventFilter) match(event scheduler.Event) bool {
+	task := event.Task
+	switch {
+	case f.TaskID != "" && task.ID != f.TaskID:
+		return false
+	case f.Type != "" && task.Type != f.Type:
+		return false
+	case f.Tag != "" && !slices.Contains(task.Tags, f.Tag):
+		return false
+	}
+	return true
+}
+
+// StreamEvents handles GET requests to follow task lifecycle events as Server-Sent Events
+//
+// A client resumes after the last event it received with the Last-Event-ID
+// header, or the last_event_id query parameter, events still in the replay
+// buffer are sent first.
+func (h *Handler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.Logger.Error.Println("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	filter := parseEventFilter(r.URL.Query())
+	after, err := lastEventID(r)
+	if err != nil {
+		h.Logger.Error.Println(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rc := http.NewResponseController(w)
+	sub, missed := h.Scheduler.Events().Subscribe(after)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	// send writes a frame and flushes it, the server write timeout is pushed back before every write
+	send := func(frame string) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(constants.ServerTimeout))
+		if _, err := fmt.Fprint(w, frame); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	if !send(": connected\n\n") {
+		return
+	}
+	for _, event := range missed {
+		if filter.match(event) && !send(eventFrame(event)) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(constants.EventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// the stream fell behind or the server is stopping, the client resumes with Last-Event-ID
+				return
+			}
+			if filter.match(event) && !send(eventFrame(event)) {
+				return
+			}
+		case <-heartbeat.C:
+			if !send(": ping\n\n") {
+				return
+			}
+		}
+	}
+}
+
+// lastEventID returns the ID of the last event a client received
+//
+// A new stream gets math.MaxUint64, so that it starts with the next event instead of the replay buffer.
+func lastEventID(r *http.Request) (uint64, error) {
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	if lastID == "" {
+		return math.MaxUint64, nil
+	}
+	after, err := strconv.ParseUint(lastID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid last event ID: %s", lastID)
+	}
+	return after, nil
+}
+
+// eventFrame returns the Server-Sent Events frame of an event
+func eventFrame(event scheduler.Event) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"id":   event.ID,
+		"type": event.Type,
+		"time": event.Time.Format(time.RFC3339Nano),
+		"task": taskResponse(event.Task),
+	})
+	return fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/scheduler"
+)
+
+// readEvents returns the id and event lines of the first n frames of a stream
+func readEvents(t *testing.T, resp *http.Response, n int) [][2]string {
+	t.Helper()
+	lines := make(chan string)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-done:
+				return
+			}
+		}
+	}()
+	var frames [][2]string
+	var frame [2]string
+	timeout := time.After(time.Second)
+	for len(frames) < n {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return frames
+			}
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				frame[0] = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				frame[1] = strings.TrimPrefix(line, "event: ")
+			case line == "" && frame[0] != "":
+				frames = append(frames, frame)
+				frame = [2]string{}
+			}
+		case <-timeout:
+			return frames
+		}
+	}
+	return frames
+}
+
+// openStream connects to the event stream of a test server, the caller closes the body
+func openStream(t *testing.T, server *httptest.Server, query, lastID string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/events?"+query, http.NoBody)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	return resp
+}
+
+func TestStreamEvents(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+	server := httptest.NewServer(http.HandlerFunc(h.StreamEvents))
+	defer server.Close()
+
+	resp := openStream(t, server, "tag=watched", "")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	task := func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "ok"}, nil
+	}
+	_, _ = s.AddTask(task, scheduler.TaskOptions{})
+	_, _ = s.AddTask(task, scheduler.TaskOptions{Tags: []string{"watched"}})
+
+	frames := readEvents(t, resp, 3)
+	want := []string{"created", "started", "succeeded"}
+	if len(frames) != len(want) {
+		t.Fatalf("expected %d events, got %v", len(want), frames)
+	}
+	for i, frame := range frames {
+		if frame[1] != want[i] {
+			t.Errorf("expected %s at %d, got %s", want[i], i, frame[1])
+		}
+	}
+
+	// the first task produced events 1 to 3, resuming after 4 replays the last two of the watched task
+	resp = openStream(t, server, "", "4")
+	defer resp.Body.Close()
+	resumed := readEvents(t, resp, 2)
+	if len(resumed) != 2 || resumed[0][0] != "5" || resumed[1][0] != "6" {
+		t.Errorf("expected events 5 and 6 on resume, got %v", resumed)
+	}
+}
+
+func TestStreamEvents_NoReplayForNewStream(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+	server := httptest.NewServer(http.HandlerFunc(h.StreamEvents))
+	defer server.Close()
+
+	task := func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "ok"}, nil
+	}
+	_, _ = s.AddTask(task, scheduler.TaskOptions{})
+	time.Sleep(20 * time.Millisecond)
+
+	resp := openStream(t, server, "", "")
+	defer resp.Body.Close()
+	_, _ = s.AddTask(task, scheduler.TaskOptions{})
+
+	// events 1 to 3 of the first task happened before the stream opened
+	frames := readEvents(t, resp, 3)
+	if len(frames) != 3 || frames[0][0] != "4" {
+		t.Errorf("expected events 4 to 6 only, got %v", frames)
+	}
+}
+
+func TestStreamEvents_InvalidLastEventID(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+
+	req := httptest.NewRequest(http.MethodGet, "/events", http.NoBody)
+	req.Header.Set("Last-Event-ID", "abc")
+	w := httptest.NewRecorder()
+	h.StreamEvents(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
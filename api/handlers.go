@@ -2,52 +2,151 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/artnikel/taskscheduler/constants"
 	"github.com/artnikel/taskscheduler/internal/logging"
+	"github.com/artnikel/taskscheduler/models"
 	"github.com/artnikel/taskscheduler/scheduler"
 	"github.com/artnikel/taskscheduler/tasks"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// retryAfterSeconds is the Retry-After value sent when the task queue is full
+	retryAfterSeconds = 1
+	// maxWait limits the wait query parameter of a task lookup
+	maxWait = time.Minute
 )
 
 // Handler provides HTTP endpoints backed by a Scheduler
 type Handler struct {
 	Scheduler *scheduler.Scheduler
 	Logger    *logging.Logger
+	// upgrader accepts WebSocket connections, see ServeWS
+	upgrader websocket.Upgrader
 }
 
 // NewHandler creates a new Handler with the given Scheduler
 func NewHandler(s *scheduler.Scheduler, logger *logging.Logger) *Handler {
-	return &Handler{Scheduler: s, Logger: logger}
-}
-
-// CreateTaskRequest represents a request to create a ping task
-type CreateTaskRequest struct {
-	Address string `json:"address"`
+	return &Handler{
+		Scheduler: s,
+		Logger:    logger,
+		upgrader:  websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024},
+	}
 }
 
 // CreatePingTask handles POST requests to add a new ping task
 func (h *Handler) CreatePingTask(w http.ResponseWriter, r *http.Request) {
+	h.createProbeTask(w, r, constants.TypePing)
+}
+
+// CreateICMPTask handles POST requests to add a new ICMP echo task
+func (h *Handler) CreateICMPTask(w http.ResponseWriter, r *http.Request) {
+	h.createProbeTask(w, r, constants.TypeICMP)
+}
+
+// createProbeTask adds a task of a type that probes an address, ping or icmp
+func (h *Handler) createProbeTask(w http.ResponseWriter, r *http.Request, taskType constants.TaskType) {
 	if r.Method != http.MethodPost {
 		h.Logger.Error.Println("method not allowed")
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	var req struct {
-		Address string `json:"address"`
+	var req CreateTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Address == "" {
+		h.Logger.Error.Println("invalid request body:", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	spec := taskSpec{
+		Spec: tasks.Spec{
+			Type:          taskType,
+			Address:       req.Address,
+			Port:          req.Port,
+			Count:         req.Count,
+			ProbeInterval: req.ProbeInterval,
+			Source:        req.Source,
+		},
+		taskRequest: req.taskRequest,
+	}
+	fn, opts, err := spec.build()
+	if err != nil {
+		h.Logger.Error.Println("invalid task options:", err)
+		http.Error(w, "invalid task options: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := h.Scheduler.AddTask(fn, opts)
+	if err != nil {
+		h.submitError(w, err)
+		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]string{"task_id": id})
+}
+
+// CreateTLSTask handles POST requests to add a new TLS certificate task
+func (h *Handler) CreateTLSTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.Logger.Error.Println("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req CreateTLSTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Address == "" {
 		h.Logger.Error.Println("invalid request body:", err)
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
-	id := h.Scheduler.AddTask(tasks.MakePingTask(req.Address))
+	spec := taskSpec{
+		Spec: tasks.Spec{
+			Type:              constants.TypeTLS,
+			Address:           req.Address,
+			Port:              req.Port,
+			ServerName:        req.ServerName,
+			MinDaysValid:      req.MinDaysValid,
+			AllowInvalidChain: req.AllowInvalidChain,
+		},
+		taskRequest: req.taskRequest,
+	}
+	fn, opts, err := spec.build()
+	if err != nil {
+		h.Logger.Error.Println("invalid task options:", err)
+		http.Error(w, "invalid task options: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := h.Scheduler.AddTask(fn, opts)
+	if err != nil {
+		h.submitError(w, err)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(map[string]string{"task_id": id})
 }
 
+// submitError writes the response for a task the scheduler did not accept
+func (h *Handler) submitError(w http.ResponseWriter, err error) {
+	h.Logger.Error.Println("failed to add task:", err)
+	switch {
+	case errors.Is(err, scheduler.ErrQueueFull):
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+		http.Error(w, "task queue is full", http.StatusTooManyRequests)
+	case errors.Is(err, scheduler.ErrStopped):
+		http.Error(w, "scheduler stopped", http.StatusServiceUnavailable)
+	default:
+		http.Error(w, "failed to add task", http.StatusInternalServerError)
+	}
+}
+
 // GetTaskStatus handles GET requests to retrieve task status by ID
 func (h *Handler) GetTaskStatus(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/tasks/")
@@ -56,53 +155,189 @@ func (h *Handler) GetTaskStatus(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "missing task ID", http.StatusBadRequest)
 		return
 	}
-	task, ok := h.Scheduler.GetTask(id)
-	if !ok {
+	wait, err := parseDuration("wait", r.URL.Query().Get("wait"))
+	if err != nil || wait > maxWait {
+		h.Logger.Error.Println("invalid wait:", r.URL.Query().Get("wait"))
+		http.Error(w, fmt.Sprintf("wait must be a duration up to %v", maxWait), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+	if wait > 0 {
+		// the server write timeout would otherwise cut a long wait short
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + constants.ServerTimeout))
+	}
+	task, err := h.Scheduler.Wait(ctx, id)
+	switch {
+	case errors.Is(err, scheduler.ErrTaskExpired):
+		h.Logger.Error.Println("task expired for ID:", id)
+		http.Error(w, "task expired", http.StatusGone)
+		return
+	case errors.Is(err, scheduler.ErrTaskNotFound):
 		h.Logger.Error.Println("task not found for ID:", id)
 		http.Error(w, "task not found", http.StatusNotFound)
 		return
+	case err != nil:
+		h.Logger.Error.Println("failed to get task", id, ":", err)
+		http.Error(w, "failed to get task", http.StatusInternalServerError)
+		return
 	}
+	if task.Err != nil {
+		h.Logger.Error.Println("Task", id, "failed with error:", task.Err)
+	}
+	resp := taskResponse(task)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// taskResponse returns the JSON form of a task
+func taskResponse(task *models.Task) map[string]interface{} {
 	resp := map[string]interface{}{
-		"id":     task.ID,
-		"status": task.Status,
+		"id":              task.ID,
+		"type":            task.Type,
+		"status":          task.Status,
+		"priority":        task.Priority,
+		"attempts":        task.Attempts,
+		"created_at":      task.CreatedAt.Format(time.RFC3339Nano),
+		"queue_wait_ms":   task.QueueWait.Milliseconds(),
+		"run_duration_ms": task.RunDuration.Milliseconds(),
+	}
+	if !task.StartedAt.IsZero() {
+		resp["started_at"] = task.StartedAt.Format(time.RFC3339Nano)
+	}
+	if !task.FinishedAt.IsZero() {
+		resp["finished_at"] = task.FinishedAt.Format(time.RFC3339Nano)
+	}
+	if !task.RunAt.IsZero() {
+		resp["run_at"] = task.RunAt.Format(time.RFC3339)
+	}
+	if task.ScheduleID != "" {
+		resp["schedule_id"] = task.ScheduleID
 	}
-	if task.Result != "" {
-		h.Logger.Error.Println("Task", id, "task result is nil")
+	if task.BatchID != "" {
+		resp["batch_id"] = task.BatchID
+	}
+	if len(task.Tags) > 0 {
+		resp["tags"] = task.Tags
+	}
+	if task.CallbackURL != "" {
+		resp["callback_url"] = task.CallbackURL
+	}
+	if len(task.AttemptErrors) > 0 {
+		resp["attempt_errors"] = task.AttemptErrors
+	}
+	if task.Result != nil {
 		resp["result"] = task.Result
 	}
 	if task.Err != nil {
-		h.Logger.Error.Println("Task", id, "failed with error:", task.Err)
 		resp["error"] = task.Err.Error()
 	}
+	return resp
+}
+
+// HandleTask dispatches requests on /tasks/{id} by method and routes /tasks/{id}/deliveries
+func (h *Handler) HandleTask(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/deliveries") {
+		h.GetDeliveries(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		h.GetTaskStatus(w, r)
+	case http.MethodDelete:
+		h.CancelTask(w, r)
+	default:
+		h.Logger.Error.Println("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CancelTask handles DELETE requests to stop a pending or running task
+func (h *Handler) CancelTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		h.Logger.Error.Println("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/tasks/")
+	if id == "" {
+		h.Logger.Error.Println("missing task ID in request")
+		http.Error(w, "missing task ID", http.StatusBadRequest)
+		return
+	}
+	err := h.Scheduler.Cancel(id)
+	switch {
+	case errors.Is(err, scheduler.ErrTaskNotFound):
+		h.Logger.Error.Println("task not found for ID:", id)
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	case errors.Is(err, scheduler.ErrTaskExpired):
+		h.Logger.Error.Println("task expired for ID:", id)
+		http.Error(w, "task expired", http.StatusGone)
+		return
+	case errors.Is(err, scheduler.ErrTaskFinished):
+		h.Logger.Error.Println("task already finished:", id)
+		http.Error(w, "task already finished", http.StatusConflict)
+		return
+	case err != nil:
+		h.Logger.Error.Println("failed to cancel task", id, ":", err)
+		http.Error(w, "failed to cancel task", http.StatusInternalServerError)
+		return
+	}
+	h.Logger.Info.Println("task cancelled:", id)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(resp)
+	_ = json.NewEncoder(w).Encode(map[string]string{"task_id": id, "status": string(constants.StatusCancelled)})
 }
 
 // GetStats handles GET requests to retrieve aggregated task statistics
 func (h *Handler) GetStats(w http.ResponseWriter, _ *http.Request) {
-	stats := h.Scheduler.GetStats()
+	stats := make(map[string]interface{})
+	for status, count := range h.Scheduler.GetStats() {
+		stats[string(status)] = count
+	}
+	stats["queue_depth"] = h.Scheduler.QueueDepth()
+	durations := h.Scheduler.Durations()
+	stats["finished_since_start"] = durations.Finished
+	stats["queue_wait_ms"] = map[string]int64{
+		"avg": durations.AvgQueueWait.Milliseconds(),
+		"max": durations.MaxQueueWait.Milliseconds(),
+	}
+	stats["run_duration_ms"] = map[string]int64{
+		"avg": durations.AvgRunDuration.Milliseconds(),
+		"max": durations.MaxRunDuration.Milliseconds(),
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(stats)
 }
 
-// CreateStatusTask handles POST requests to add a new HTTP GET task
+// CreateStatusTask handles POST requests to add a new HTTP check task
 func (h *Handler) CreateStatusTask(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		h.Logger.Error.Println("method not allowed")
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	var req struct {
-		URL string `json:"url"`
-	}
+	var req CreateHTTPTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
 		h.Logger.Error.Println("invalid request body:", err)
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
-	id := h.Scheduler.AddTask(tasks.MakeGetStatusTask(req.URL))
+	spec := taskSpec{Spec: tasks.Spec{Type: constants.TypeHTTPStatus, URL: req.URL, HTTPSpec: req.HTTPSpec}, taskRequest: req.taskRequest}
+	fn, opts, err := spec.build()
+	if err != nil {
+		h.Logger.Error.Println("invalid task options:", err)
+		http.Error(w, "invalid task options: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := h.Scheduler.AddTask(fn, opts)
+	if err != nil {
+		h.submitError(w, err)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(map[string]string{"task_id": id})
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"log"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/artnikel/taskscheduler/constants"
 	"github.com/artnikel/taskscheduler/internal/logging"
+	"github.com/artnikel/taskscheduler/models"
 	"github.com/artnikel/taskscheduler/scheduler"
 )
 
@@ -62,10 +64,10 @@ func TestGetTaskStatus_Valid(t *testing.T) {
 	logger := NewLoggerForTest()
 	h := NewHandler(s, logger)
 
-	id := s.AddTask(func() (string, error) {
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
 		time.Sleep(10 * time.Millisecond)
-		return "pong", nil
-	})
+		return &models.Result{Summary: "pong"}, nil
+	}, scheduler.TaskOptions{})
 	time.Sleep(50 * time.Millisecond)
 
 	req := httptest.NewRequest(http.MethodGet, "/tasks/"+id, http.NoBody)
@@ -81,6 +83,15 @@ func TestGetTaskStatus_Valid(t *testing.T) {
 	if data["status"] != string(constants.StatusDone) {
 		t.Errorf("expected status 'done', got %v", data["status"])
 	}
+	for _, field := range []string{"created_at", "started_at", "finished_at"} {
+		value, _ := data[field].(string)
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			t.Errorf("expected RFC 3339 %s, got %v", field, data[field])
+		}
+	}
+	if ms, _ := data["run_duration_ms"].(float64); ms < 10 {
+		t.Errorf("expected run_duration_ms of at least 10, got %v", data["run_duration_ms"])
+	}
 }
 
 func TestGetTaskStatus_NotFound(t *testing.T) {
@@ -103,10 +114,10 @@ func TestGetStats(t *testing.T) {
 	logger := NewLoggerForTest()
 	h := NewHandler(s, logger)
 
-	_ = s.AddTask(func() (string, error) {
+	_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
 		time.Sleep(10 * time.Millisecond)
-		return "result", nil
-	})
+		return &models.Result{Summary: "result"}, nil
+	}, scheduler.TaskOptions{})
 	time.Sleep(50 * time.Millisecond)
 
 	req := httptest.NewRequest(http.MethodGet, "/stats", http.NoBody)
@@ -143,6 +154,33 @@ func TestCreateStatusTask_Valid(t *testing.T) {
 	}
 }
 
+func TestCreateStatusTask_Request(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"url": "http://127.0.0.1:1", "method": "POST", "headers": {"Content-Type": "application/json"}, "body": "{}", "basic_auth": {"username": "user", "password": "secret"}, "redirects": "none", "expected_status": ["2xx", "301"]}`, http.StatusCreated},
+		{`{"url": "http://127.0.0.1:1", "bearer_token": "token", "max_redirects": 3}`, http.StatusCreated},
+		{`{"url": "http://127.0.0.1:1", "method": "TRACE"}`, http.StatusBadRequest},
+		{`{"url": "http://127.0.0.1:1", "expected_status": ["7xx"]}`, http.StatusBadRequest},
+		{`{"url": "http://127.0.0.1:1", "basic_auth": {"username": "user"}, "bearer_token": "token"}`, http.StatusBadRequest},
+		{`{"url": "http://127.0.0.1:1", "assertions": [{"type": "json_equals", "path": "$.status", "value": "up"}, {"type": "max_body_bytes", "value": 4096}]}`, http.StatusCreated},
+		{`{"url": "http://127.0.0.1:1", "assertions": [{"type": "header_matches", "pattern": "json"}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/tasks/http/status", bytes.NewBufferString(tt.body))
+		w := httptest.NewRecorder()
+		h.CreateStatusTask(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.body, tt.want, w.Code)
+		}
+	}
+}
+
 func TestCreateStatusTask_InvalidMethod(t *testing.T) {
 	s := scheduler.NewScheduler(1)
 	logger := NewLoggerForTest()
@@ -175,3 +213,369 @@ func TestCreateStatusTask_InvalidBody(t *testing.T) {
 		t.Fatalf("expected status 400 Bad Request, got %d", resp.StatusCode)
 	}
 }
+
+func TestCreatePingTask_InvalidTimeout(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	body := []byte(`{"address": "example.com", "timeout": "soon"}`)
+	req := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.CreatePingTask(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreatePingTask_Probes(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"address": "127.0.0.1", "port": 1, "count": 3, "probe_interval": "10ms", "source": "127.0.0.1"}`, http.StatusCreated},
+		{`{"address": "example.com", "port": 65536}`, http.StatusBadRequest},
+		{`{"address": "example.com", "count": 1000}`, http.StatusBadRequest},
+		{`{"address": "example.com", "probe_interval": "-1s"}`, http.StatusBadRequest},
+		{`{"address": "example.com", "source": "not-an-ip"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/tasks/ping", bytes.NewBufferString(tt.body))
+		w := httptest.NewRecorder()
+		h.CreatePingTask(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.body, tt.want, w.Code)
+		}
+	}
+}
+
+func TestCreateICMPTask(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+
+	tests := []struct {
+		method string
+		body   string
+		want   int
+	}{
+		{http.MethodPost, `{"address": "127.0.0.1", "count": 3, "probe_interval": "10ms"}`, http.StatusCreated},
+		{http.MethodPost, `{"address": "127.0.0.1", "port": 80}`, http.StatusBadRequest},
+		{http.MethodPost, `{"count": 3}`, http.StatusBadRequest},
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/tasks/icmp", bytes.NewBufferString(tt.body))
+		w := httptest.NewRecorder()
+		h.CreateICMPTask(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.body, tt.want, w.Code)
+		}
+	}
+}
+
+func TestCreateTLSTask(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+
+	tests := []struct {
+		method string
+		body   string
+		want   int
+	}{
+		{http.MethodPost, `{"address": "127.0.0.1", "port": 1, "server_name": "example.com", "min_days_valid": 30}`, http.StatusCreated},
+		{http.MethodPost, `{"address": "127.0.0.1", "min_days_valid": 0}`, http.StatusCreated},
+		{http.MethodPost, `{"address": "127.0.0.1", "min_days_valid": -1}`, http.StatusBadRequest},
+		{http.MethodPost, `{"port": 443}`, http.StatusBadRequest},
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/tasks/tls", bytes.NewBufferString(tt.body))
+		w := httptest.NewRecorder()
+		h.CreateTLSTask(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.body, tt.want, w.Code)
+		}
+	}
+}
+
+func TestCancelTask_Running(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	id, _ := s.AddTask(func(ctx context.Context) (*models.Result, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, scheduler.TaskOptions{Timeout: time.Second})
+	time.Sleep(20 * time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodDelete, "/tasks/"+id, http.NoBody)
+	w := httptest.NewRecorder()
+	h.HandleTask(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	task, _ := s.GetTask(id)
+	if task.Status != constants.StatusCancelled {
+		t.Errorf("expected status 'cancelled', got %v", task.Status)
+	}
+}
+
+func TestCancelTask_Finished(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "pong"}, nil
+	}, scheduler.TaskOptions{})
+	time.Sleep(20 * time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodDelete, "/tasks/"+id, http.NoBody)
+	w := httptest.NewRecorder()
+	h.CancelTask(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", resp.StatusCode)
+	}
+}
+
+func TestCancelTask_NotFound(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	req := httptest.NewRequest(http.MethodDelete, "/tasks/nonexistent", http.NoBody)
+	w := httptest.NewRecorder()
+	h.CancelTask(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreateStatusTask_InvalidRetry(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	body := []byte(`{"url": "http://example.com", "retry": {"max_attempts": 3, "jitter": 2}}`)
+	req := httptest.NewRequest(http.MethodPost, "/status-tasks", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	h.CreateStatusTask(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected status 400 Bad Request, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreatePingTask_Delayed(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	body := []byte(`{"address": "127.0.0.1", "delay": "1h"}`)
+	req := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.CreatePingTask(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var data map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&data)
+
+	req = httptest.NewRequest(http.MethodGet, "/tasks/"+data["task_id"], http.NoBody)
+	w = httptest.NewRecorder()
+	h.GetTaskStatus(w, req)
+
+	var task map[string]interface{}
+	_ = json.NewDecoder(w.Result().Body).Decode(&task)
+	if task["status"] != string(constants.StatusScheduled) || task["run_at"] == nil {
+		t.Errorf("unexpected task: %v", task)
+	}
+}
+
+func TestCreateStatusTask_InvalidRunAt(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	for _, body := range []string{
+		`{"url": "http://example.com", "run_at": "tomorrow"}`,
+		`{"url": "http://example.com", "run_at": "2030-01-01T00:00:00Z", "delay": "1m"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/status-tasks", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		h.CreateStatusTask(w, req)
+
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("body %s: expected 400, got %d", body, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestCreatePingTask_QueueFull(t *testing.T) {
+	s := scheduler.NewScheduler(1, scheduler.WithMaxQueue(1))
+	defer s.Stop()
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	release := make(chan struct{})
+	defer close(release)
+	for range 2 {
+		_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+			<-release
+			return &models.Result{Summary: "ok"}, nil
+		}, scheduler.TaskOptions{})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	body := []byte(`{"address": "127.0.0.1"}`)
+	req := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.CreatePingTask(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+}
+
+func TestGetStats_QueueDepth(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	release := make(chan struct{})
+	defer close(release)
+	for _, priority := range []int{0, 0, 7} {
+		_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+			<-release
+			return &models.Result{Summary: "result"}, nil
+		}, scheduler.TaskOptions{Priority: priority})
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats", http.NoBody)
+	w := httptest.NewRecorder()
+	h.GetStats(w, req)
+
+	var data struct {
+		Running    int            `json:"running"`
+		Pending    int            `json:"pending"`
+		QueueDepth map[string]int `json:"queue_depth"`
+	}
+	_ = json.NewDecoder(w.Result().Body).Decode(&data)
+	if data.Running != 1 || data.Pending != 2 {
+		t.Errorf("unexpected stats: %+v", data)
+	}
+	if data.QueueDepth["0"]+data.QueueDepth["7"] != 2 || len(data.QueueDepth) != 10 {
+		t.Errorf("unexpected queue depth: %v", data.QueueDepth)
+	}
+}
+
+func TestCreatePingTask_InvalidPriority(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	body := []byte(`{"address": "example.com", "priority": 10}`)
+	req := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.CreatePingTask(w, req)
+
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestGetTaskStatus_Expired(t *testing.T) {
+	s := scheduler.NewScheduler(1, scheduler.WithRetention(scheduler.RetentionPolicy{
+		MaxCount:      1,
+		SweepInterval: 20 * time.Millisecond,
+	}))
+	defer s.Stop()
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	first, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "pong"}, nil
+	}, scheduler.TaskOptions{})
+	time.Sleep(10 * time.Millisecond)
+	_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "pong"}, nil
+	}, scheduler.TaskOptions{})
+	time.Sleep(50 * time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks/"+first, http.NoBody)
+	w := httptest.NewRecorder()
+	h.GetTaskStatus(w, req)
+
+	if w.Result().StatusCode != http.StatusGone {
+		t.Fatalf("expected 410, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestGetTaskStatus_Wait(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		time.Sleep(100 * time.Millisecond)
+		return &models.Result{Summary: "pong"}, nil
+	}, scheduler.TaskOptions{})
+
+	tests := []struct {
+		wait   string
+		code   int
+		status constants.TaskStatus
+	}{
+		{"20ms", http.StatusOK, constants.StatusRunning},
+		{"5s", http.StatusOK, constants.StatusDone},
+		{"soon", http.StatusBadRequest, ""},
+		{"2m", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		req := httptest.NewRequest(http.MethodGet, "/tasks/"+id+"?wait="+tt.wait, http.NoBody)
+		w := httptest.NewRecorder()
+		h.GetTaskStatus(w, req)
+
+		resp := w.Result()
+		if resp.StatusCode != tt.code {
+			t.Fatalf("wait=%s: expected %d, got %d", tt.wait, tt.code, resp.StatusCode)
+		}
+		if tt.status == "" {
+			continue
+		}
+		var data map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&data)
+		if data["status"] != string(tt.status) {
+			t.Errorf("wait=%s: expected status %s, got %v", tt.wait, tt.status, data["status"])
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("wait=%s: expected to return once the task finished, took %v", tt.wait, time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/store"
+)
+
+const (
+	// defaultPageSize is the number of tasks listed when no limit is given
+	defaultPageSize = 50
+	// maxPageSize is the largest accepted limit
+	maxPageSize = 500
+)
+
+// cursorToken is the decoded form of the opaque cursor returned by the list endpoint
+//
+// The sort order is part of the token so that a cursor cannot be used with another order.
+type cursorToken struct {
+	SortBy     store.SortField `json:"s"`
+	Descending bool            `json:"d,omitempty"`
+	Time       int64           `json:"t"`
+	ID         string          `json:"id"`
+}
+
+// encodeCursor returns the token that continues a listing after the given position
+func encodeCursor(filter *store.Filter, cursor store.Cursor) string {
+	token := cursorToken{SortBy: filter.SortBy, Descending: filter.Descending, ID: cursor.ID}
+	if !cursor.Time.IsZero() {
+		token.Time = cursor.Time.UnixNano()
+	}
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a token from encodeCursor and checks it matches the order of the filter
+func decodeCursor(filter *store.Filter, value string) (*store.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var token cursorToken
+	if err := json.Unmarshal(data, &token); err != nil || token.ID == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	if token.SortBy != filter.SortBy || token.Descending != filter.Descending {
+		return nil, errors.New("cursor does not match sort")
+	}
+	cursor := &store.Cursor{ID: token.ID}
+	if token.Time != 0 {
+		cursor.Time = time.Unix(0, token.Time)
+	}
+	return cursor, nil
+}
+
+// HandleTasks dispatches requests on /tasks by method
+func (h *Handler) HandleTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.Logger.Error.Println("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.ListTasks(w, r)
+}
+
+// ListTasks handles GET requests to list tasks a page at a time
+func (h *Handler) ListTasks(w http.ResponseWriter, r *http.Request) {
+	filter, err := listFilter(r.URL.Query())
+	if err != nil {
+		h.Logger.Error.Println("invalid list query:", err)
+		http.Error(w, "invalid query: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := filter.Limit
+	// one extra task tells whether there is a next page
+	filter.Limit++
+	list, err := h.Scheduler.List(filter)
+	if err != nil {
+		h.Logger.Error.Println("failed to list tasks:", err)
+		http.Error(w, "failed to list tasks", http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{}
+	if len(list) > limit {
+		list = list[:limit]
+		resp["next_cursor"] = encodeCursor(&filter, filter.CursorOf(list[limit-1]))
+	}
+	items := make([]map[string]interface{}, 0, len(list))
+	for _, task := range list {
+		items = append(items, taskResponse(task))
+	}
+	resp["tasks"] = items
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// listFilter builds a store filter from the list query parameters
+func listFilter(query url.Values) (store.Filter, error) {
+	filter := store.Filter{
+		Type:   constants.TaskType(query.Get("type")),
+		Tag:    query.Get("tag"),
+		SortBy: store.SortCreated,
+		Limit:  defaultPageSize,
+	}
+	for _, value := range query["status"] {
+		for _, status := range strings.Split(value, ",") {
+			filter.Statuses = append(filter.Statuses, constants.TaskStatus(status))
+		}
+	}
+	var err error
+	if filter.CreatedAfter, err = parseTime("created_after", query.Get("created_after")); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBefore, err = parseTime("created_before", query.Get("created_before")); err != nil {
+		return filter, err
+	}
+	if sortBy := query.Get("sort"); sortBy != "" {
+		filter.Descending = strings.HasPrefix(sortBy, "-")
+		filter.SortBy = store.SortField(strings.TrimPrefix(sortBy, "-"))
+		if filter.SortBy != store.SortCreated && filter.SortBy != store.SortFinished {
+			return filter, fmt.Errorf("sort must be %s or %s, optionally prefixed with -", store.SortCreated, store.SortFinished)
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		filter.Limit = limit
+	}
+	if value := query.Get("cursor"); value != "" {
+		if filter.After, err = decodeCursor(&filter, value); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+// parseTime parses an optional RFC 3339 query parameter
+func parseTime(field, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %w", field, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/scheduler"
+)
+
+// listPage requests one page of the list endpoint
+func listPage(t *testing.T, h *Handler, query url.Values) (int, []string, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/tasks?"+query.Encode(), http.NoBody)
+	w := httptest.NewRecorder()
+	h.HandleTasks(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil, ""
+	}
+	var data struct {
+		Tasks []struct {
+			ID string `json:"id"`
+		} `json:"tasks"`
+		NextCursor string `json:"next_cursor"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&data)
+	ids := make([]string, 0, len(data.Tasks))
+	for _, task := range data.Tasks {
+		ids = append(ids, task.ID)
+	}
+	return resp.StatusCode, ids, data.NextCursor
+}
+
+func TestListTasks_Pagination(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+
+	var want []string
+	for i := range 5 {
+		opts := scheduler.TaskOptions{}
+		if i%2 == 0 {
+			opts.Tags = []string{"even"}
+		}
+		id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+			return &models.Result{Summary: "ok"}, nil
+		}, opts)
+		want = append(want, id)
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	tests := []struct {
+		name  string
+		query url.Values
+		want  []string
+	}{
+		{"created", url.Values{"limit": {"2"}}, want},
+		{"created descending", url.Values{"limit": {"2"}, "sort": {"-created_at"}}, []string{want[4], want[3], want[2], want[1], want[0]}},
+		{"finished", url.Values{"limit": {"3"}, "sort": {"finished_at"}, "status": {"done,failed"}}, want},
+		{"tag", url.Values{"tag": {"even"}}, []string{want[0], want[2], want[4]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			query := tt.query
+			for range 10 {
+				code, ids, next := listPage(t, h, query)
+				if code != http.StatusOK {
+					t.Fatalf("expected 200, got %d", code)
+				}
+				got = append(got, ids...)
+				if next == "" {
+					break
+				}
+				query.Set("cursor", next)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d tasks, got %d", len(tt.want), len(got))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %s at %d, got %s", tt.want[i], i, got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestListTasks_InvalidQuery(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+
+	_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "ok"}, nil
+	}, scheduler.TaskOptions{})
+	_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "ok"}, nil
+	}, scheduler.TaskOptions{})
+	time.Sleep(20 * time.Millisecond)
+	_, _, next := listPage(t, h, url.Values{"limit": {"1"}})
+
+	for _, query := range []url.Values{
+		{"sort": {"priority"}},
+		{"limit": {"0"}},
+		{"created_after": {"yesterday"}},
+		{"cursor": {"not-a-cursor"}},
+		{"cursor": {next}, "sort": {"-created_at"}},
+	} {
+		if code, _, _ := listPage(t, h, query); code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %v, got %d", query, code)
+		}
+	}
+}
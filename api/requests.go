@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/scheduler"
+	"github.com/artnikel/taskscheduler/tasks"
+)
+
+// CreateTaskRequest represents a request to create a ping or icmp task
+type CreateTaskRequest struct {
+	Address       string `json:"address"`
+	Port          int    `json:"port,omitempty"`
+	Count         int    `json:"count,omitempty"`
+	ProbeInterval string `json:"probe_interval,omitempty"`
+	Source        string `json:"source,omitempty"`
+	taskRequest
+}
+
+// CreateTLSTaskRequest represents a request to create a tls task
+type CreateTLSTaskRequest struct {
+	Address           string `json:"address"`
+	Port              int    `json:"port,omitempty"`
+	ServerName        string `json:"server_name,omitempty"`
+	MinDaysValid      *int   `json:"min_days_valid,omitempty"`
+	AllowInvalidChain bool   `json:"allow_invalid_chain,omitempty"`
+	taskRequest
+}
+
+// CreateHTTPTaskRequest represents a request to create an http_status task
+type CreateHTTPTaskRequest struct {
+	URL string `json:"url"`
+	tasks.HTTPSpec
+	taskRequest
+}
+
+// taskRequest holds the submission settings shared by every task type
+type taskRequest struct {
+	Timeout  string        `json:"timeout,omitempty"`
+	Retry    *retryRequest `json:"retry,omitempty"`
+	RunAt    string        `json:"run_at,omitempty"`
+	Delay    string        `json:"delay,omitempty"`
+	Priority *int          `json:"priority,omitempty"`
+	Tags     []string      `json:"tags,omitempty"`
+	// CallbackURL receives the outcome of the task when it is done or failed
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// taskSpec describes a task of any type for endpoints that accept several types
+type taskSpec struct {
+	tasks.Spec
+	taskRequest
+}
+
+// build validates the spec and returns the task function with its options
+func (t *taskSpec) build() (scheduler.TaskFunc, scheduler.TaskOptions, error) {
+	opts, err := t.options()
+	if err != nil {
+		return nil, opts, err
+	}
+	fn, err := t.Build()
+	if err != nil {
+		return nil, opts, err
+	}
+	opts.Type = t.Type
+	if opts.Timeout == 0 {
+		opts.Timeout = t.MinTimeout()
+	}
+	if opts.Spec, err = json.Marshal(t.Spec); err != nil {
+		return nil, opts, err
+	}
+	return fn, opts, nil
+}
+
+// retryRequest is the optional retry policy of a task request
+type retryRequest struct {
+	MaxAttempts    int     `json:"max_attempts"`
+	InitialBackoff string  `json:"initial_backoff"`
+	Multiplier     float64 `json:"multiplier"`
+	MaxBackoff     string  `json:"max_backoff"`
+	Jitter         float64 `json:"jitter"`
+}
+
+// options converts the shared request settings into scheduler task options
+func (r *taskRequest) options() (scheduler.TaskOptions, error) {
+	var opts scheduler.TaskOptions
+	timeout, err := parseDuration("timeout", r.Timeout)
+	if err != nil {
+		return opts, err
+	}
+	opts.Timeout = timeout
+	opts.Tags = r.Tags
+	if r.CallbackURL != "" {
+		callback, err := url.Parse(r.CallbackURL)
+		if err != nil || (callback.Scheme != "http" && callback.Scheme != "https") || callback.Host == "" {
+			return opts, errors.New("callback_url must be an absolute http or https URL")
+		}
+		opts.CallbackURL = r.CallbackURL
+	}
+	opts.Priority = constants.DefaultPriority
+	if r.Priority != nil {
+		if *r.Priority < constants.MinPriority || *r.Priority > constants.MaxPriority {
+			return opts, fmt.Errorf("priority must be between %d and %d", constants.MinPriority, constants.MaxPriority)
+		}
+		opts.Priority = *r.Priority
+	}
+	if opts.RunAt, err = r.runAt(); err != nil {
+		return opts, err
+	}
+	if r.Retry != nil {
+		policy, err := r.Retry.policy()
+		if err != nil {
+			return opts, err
+		}
+		opts.Retry = &policy
+	}
+	return opts, nil
+}
+
+// runAt resolves the absolute or relative start time of a delayed task
+func (r *taskRequest) runAt() (time.Time, error) {
+	if r.RunAt != "" && r.Delay != "" {
+		return time.Time{}, errors.New("run_at and delay are mutually exclusive")
+	}
+	if r.RunAt != "" {
+		runAt, err := time.Parse(time.RFC3339, r.RunAt)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid run_at: %w", err)
+		}
+		return runAt, nil
+	}
+	delay, err := parseDuration("delay", r.Delay)
+	if err != nil || delay == 0 {
+		return time.Time{}, err
+	}
+	return time.Now().Add(delay), nil
+}
+
+// policy validates the request and builds a scheduler retry policy
+func (r *retryRequest) policy() (scheduler.RetryPolicy, error) {
+	initial, err := parseDuration("initial_backoff", r.InitialBackoff)
+	if err != nil {
+		return scheduler.RetryPolicy{}, err
+	}
+	maxBackoff, err := parseDuration("max_backoff", r.MaxBackoff)
+	if err != nil {
+		return scheduler.RetryPolicy{}, err
+	}
+	if r.MaxAttempts < 1 {
+		return scheduler.RetryPolicy{}, fmt.Errorf("max_attempts must be at least 1")
+	}
+	if r.Multiplier < 0 {
+		return scheduler.RetryPolicy{}, fmt.Errorf("multiplier must not be negative")
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return scheduler.RetryPolicy{}, fmt.Errorf("jitter must be between 0 and 1")
+	}
+	return scheduler.RetryPolicy{
+		MaxAttempts:    r.MaxAttempts,
+		InitialBackoff: initial,
+		Multiplier:     r.Multiplier,
+		MaxBackoff:     maxBackoff,
+		Jitter:         r.Jitter,
+	}, nil
+}
+
+// parseDuration converts an optional duration string from a request body
+func parseDuration(field, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", field, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid %s: negative duration %q", field, value)
+	}
+	return d, nil
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/scheduler"
+)
+
+const (
+	// defaultNextRuns is the number of fire times returned for a schedule
+	defaultNextRuns = 5
+	// maxNextRuns limits the next query parameter of a schedule lookup
+	maxNextRuns = 100
+)
+
+// HandleSchedules dispatches requests on /schedules by method
+func (h *Handler) HandleSchedules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.ListSchedules(w, r)
+	case http.MethodPost:
+		h.CreateSchedule(w, r)
+	default:
+		h.Logger.Error.Println("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleSchedule dispatches requests on /schedules/{id} by method
+func (h *Handler) HandleSchedule(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetSchedule(w, r)
+	case http.MethodDelete:
+		h.DeleteSchedule(w, r)
+	default:
+		h.Logger.Error.Println("method not allowed")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// CreateSchedule handles POST requests to start a recurring task
+func (h *Handler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		taskSpec
+		Interval      string `json:"interval"`
+		Cron          string `json:"cron"`
+		Timezone      string `json:"timezone"`
+		Jitter        string `json:"jitter"`
+		SkipIfRunning bool   `json:"skip_if_running"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Logger.Error.Println("invalid request body:", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	fn, opts, err := req.build()
+	if err != nil {
+		h.Logger.Error.Println("invalid task spec:", err)
+		http.Error(w, "invalid task spec: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	interval, err := parseDuration("interval", req.Interval)
+	if err == nil && interval > 0 && interval < constants.MinScheduleInterval {
+		err = fmt.Errorf("interval must be at least %v", constants.MinScheduleInterval)
+	}
+	if err != nil {
+		h.Logger.Error.Println("invalid schedule:", err)
+		http.Error(w, "invalid schedule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	jitter, err := parseDuration("jitter", req.Jitter)
+	if err != nil {
+		h.Logger.Error.Println("invalid schedule:", err)
+		http.Error(w, "invalid schedule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := h.Scheduler.AddSchedule(scheduler.ScheduleSpec{
+		Interval:      interval,
+		Cron:          req.Cron,
+		Timezone:      req.Timezone,
+		Jitter:        jitter,
+		SkipIfRunning: req.SkipIfRunning,
+		Task:          fn,
+		Options:       opts,
+	})
+	if err != nil {
+		h.Logger.Error.Println("failed to add schedule:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.Logger.Info.Println("schedule created:", id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]string{"schedule_id": id})
+}
+
+// ListSchedules handles GET requests to list all recurring tasks
+func (h *Handler) ListSchedules(w http.ResponseWriter, _ *http.Request) {
+	schedules := h.Scheduler.ListSchedules()
+	resp := make([]map[string]interface{}, 0, len(schedules))
+	for i := range schedules {
+		resp = append(resp, scheduleResponse(&schedules[i]))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// GetSchedule handles GET requests to retrieve a recurring task by ID with its next fire times
+func (h *Handler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/schedules/")
+	count := defaultNextRuns
+	if value := r.URL.Query().Get("next"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxNextRuns {
+			h.Logger.Error.Println("invalid next parameter:", value)
+			http.Error(w, fmt.Sprintf("next must be between 1 and %d", maxNextRuns), http.StatusBadRequest)
+			return
+		}
+		count = n
+	}
+	sc, ok := h.Scheduler.GetSchedule(id)
+	if !ok {
+		h.Logger.Error.Println("schedule not found for ID:", id)
+		http.Error(w, "schedule not found", http.StatusNotFound)
+		return
+	}
+	runs, err := h.Scheduler.NextRuns(id, count)
+	if err != nil {
+		h.Logger.Error.Println("schedule not found for ID:", id)
+		http.Error(w, "schedule not found", http.StatusNotFound)
+		return
+	}
+	nextRuns := make([]string, 0, len(runs))
+	for _, run := range runs {
+		nextRuns = append(nextRuns, run.Format(time.RFC3339))
+	}
+	resp := scheduleResponse(&sc)
+	resp["next_runs"] = nextRuns
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// DeleteSchedule handles DELETE requests to stop a recurring task
+func (h *Handler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/schedules/")
+	if err := h.Scheduler.StopSchedule(id); err != nil {
+		h.Logger.Error.Println("schedule not found for ID:", id)
+		http.Error(w, "schedule not found", http.StatusNotFound)
+		return
+	}
+	h.Logger.Info.Println("schedule stopped:", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// scheduleResponse converts a schedule into its JSON representation
+func scheduleResponse(sc *models.Schedule) map[string]interface{} {
+	resp := map[string]interface{}{
+		"id":              sc.ID,
+		"type":            sc.Type,
+		"skip_if_running": sc.SkipIfRunning,
+		"created_at":      sc.CreatedAt.Format(time.RFC3339),
+		"runs":            sc.Runs,
+		"skipped":         sc.Skipped,
+	}
+	if sc.Interval > 0 {
+		resp["interval"] = sc.Interval.String()
+	}
+	if sc.Cron != "" {
+		resp["cron"] = sc.Cron
+	}
+	if sc.Timezone != "" {
+		resp["timezone"] = sc.Timezone
+	}
+	if sc.Jitter > 0 {
+		resp["jitter"] = sc.Jitter.String()
+	}
+	if !sc.NextRun.IsZero() {
+		resp["next_run"] = sc.NextRun.Format(time.RFC3339)
+	}
+	if sc.LastTaskID != "" {
+		resp["last_task_id"] = sc.LastTaskID
+	}
+	return resp
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/artnikel/taskscheduler/scheduler"
+)
+
+func TestCreateSchedule_Valid(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	body := []byte(`{"type": "ping", "address": "127.0.0.1", "interval": "1m", "skip_if_running": true}`)
+	req := httptest.NewRequest(http.MethodPost, "/schedules", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.HandleSchedules(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var data map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&data)
+	id := data["schedule_id"]
+	if id == "" {
+		t.Fatal("schedule_id not returned")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/schedules", http.NoBody)
+	w = httptest.NewRecorder()
+	h.HandleSchedules(w, req)
+	var list []map[string]interface{}
+	_ = json.NewDecoder(w.Result().Body).Decode(&list)
+	if len(list) != 1 || list[0]["id"] != id || list[0]["interval"] != "1m0s" {
+		t.Errorf("unexpected schedules: %v", list)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/schedules/"+id, http.NoBody)
+	w = httptest.NewRecorder()
+	h.HandleSchedule(w, req)
+	if w.Result().StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Result().StatusCode)
+	}
+	if len(s.ListSchedules()) != 0 {
+		t.Error("schedule should be removed")
+	}
+}
+
+func TestCreateSchedule_Invalid(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	for _, body := range []string{
+		`{"type": "ping", "address": "127.0.0.1"}`,
+		`{"type": "ping", "address": "127.0.0.1", "interval": "1ns"}`,
+		`{"type": "ping", "address": "127.0.0.1", "interval": "999ms"}`,
+		`{"type": "unknown", "interval": "1s"}`,
+		`{"type": "http_status", "interval": "1s"}`,
+		`{"type": "ping", "address": "127.0.0.1", "cron": "61 * * * *"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/schedules", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		h.HandleSchedules(w, req)
+
+		if w.Result().StatusCode != http.StatusBadRequest {
+			t.Errorf("body %s: expected 400, got %d", body, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestDeleteSchedule_NotFound(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	req := httptest.NewRequest(http.MethodDelete, "/schedules/nonexistent", http.NoBody)
+	w := httptest.NewRecorder()
+	h.HandleSchedule(w, req)
+
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestGetSchedule_NextRuns(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	logger := NewLoggerForTest()
+	h := NewHandler(s, logger)
+
+	body := []byte(`{"type": "http_status", "url": "http://127.0.0.1", "cron": "0 9 * * MON-FRI", "timezone": "Europe/Berlin"}`)
+	req := httptest.NewRequest(http.MethodPost, "/schedules", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	h.HandleSchedules(w, req)
+	if w.Result().StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", w.Result().StatusCode)
+	}
+	var created map[string]string
+	_ = json.NewDecoder(w.Result().Body).Decode(&created)
+	defer func() { _ = s.StopSchedule(created["schedule_id"]) }()
+
+	req = httptest.NewRequest(http.MethodGet, "/schedules/"+created["schedule_id"]+"?next=3", http.NoBody)
+	w = httptest.NewRecorder()
+	h.HandleSchedule(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var data struct {
+		Cron     string   `json:"cron"`
+		NextRuns []string `json:"next_runs"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&data)
+	if data.Cron != "0 9 * * MON-FRI" || len(data.NextRuns) != 3 {
+		t.Errorf("unexpected schedule: %+v", data)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/schedules/"+created["schedule_id"]+"?next=0", http.NoBody)
+	w = httptest.NewRecorder()
+	h.HandleSchedule(w, req)
+	if w.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Result().StatusCode)
+	}
+}
@@ -0,0 +1,331 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/scheduler"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsPingInterval is how often the server pings an idle WebSocket connection
+	wsPingInterval = 30 * time.Second
+	// wsPongWait is how long the server waits for any message or pong before dropping a connection
+	wsPongWait = 2 * wsPingInterval
+	// wsWriteWait limits the time to write one message
+	wsWriteWait = 10 * time.Second
+	// wsMaxMessageSize limits the size of a client message
+	wsMaxMessageSize = 64 << 10
+	// wsSendBuffer is the number of outgoing messages queued per connection
+	wsSendBuffer = 64
+	// wsMaxSubscriptions limits the tasks one connection waits for
+	wsMaxSubscriptions = 1000
+)
+
+// WebSocket message types
+const (
+	wsSubmit       = "submit"
+	wsSubmitted    = "submitted"
+	wsSubscribe    = "subscribe"
+	wsSubscribed   = "subscribed"
+	wsUnsubscribe  = "unsubscribe"
+	wsUnsubscribed = "unsubscribed"
+	wsCancel       = "cancel"
+	wsCancelled    = "cancelled"
+	wsPing         = "ping"
+	wsPong         = "pong"
+	wsResult       = "result"
+	wsError        = "error"
+)
+
+// errTooManySubscriptions is returned when a connection waits for wsMaxSubscriptions tasks
+var errTooManySubscriptions = errors.New("too many subscriptions")
+
+// wsRequest is a message sent by a WebSocket client
+type wsRequest struct {
+	Type   string    `json:"type"`
+	ID     string    `json:"id,omitempty"`
+	TaskID string    `json:"task_id,omitempty"`
+	Task   *taskSpec `json:"task,omitempty"`
+}
+
+// wsMessage is a message sent to a WebSocket client, replies carry the ID of their request
+type wsMessage struct {
+	Type   string                 `json:"type"`
+	ID     string                 `json:"id,omitempty"`
+	TaskID string                 `json:"task_id,omitempty"`
+	Task   map[string]interface{} `json:"task,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// wsConn is the state of one WebSocket connection
+//
+// Requests are read and answered one at a time. When the client does not read
+// its messages the outgoing queue fills up, the server then stops reading
+// requests until there is room again. Results missed meanwhile are looked up
+// in the task store once the connection catches up.
+type wsConn struct {
+	h    *Handler
+	conn *websocket.Conn
+	out  chan wsMessage
+	done chan struct{}
+	// lock guards subscribed, it is held while a task is submitted so its result cannot arrive before it is tracked
+	lock       sync.Mutex
+	subscribed map[string]struct{}
+	closeOnce  sync.Once
+}
+
+// ServeWS handles WebSocket connections to submit tasks and receive their results
+func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already replied with an error
+		h.Logger.Error.Println("websocket upgrade failed:", err)
+		return
+	}
+	c := &wsConn{
+		h:          h,
+		conn:       conn,
+		out:        make(chan wsMessage, wsSendBuffer),
+		done:       make(chan struct{}),
+		subscribed: make(map[string]struct{}),
+	}
+	// subscribe before reading requests so that no result of a task submitted on the connection is published unseen
+	sub, _ := h.Scheduler.Events().Subscribe(math.MaxUint64)
+	go c.writeLoop()
+	go c.pushResults(sub)
+	c.readLoop()
+}
+
+// close ends the connection, it is safe to call more than once
+func (c *wsConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
+
+// send queues a message and blocks while the queue is full, it returns false once the connection is closed
+func (c *wsConn) send(msg wsMessage) bool {
+	select {
+	case c.out <- msg:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+// readLoop answers client requests until the connection fails or goes silent
+func (c *wsConn) readLoop() {
+	defer c.close()
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.h.Logger.Error.Println("websocket read failed:", err)
+			}
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			if !c.send(wsMessage{Type: wsError, Error: "invalid message"}) {
+				return
+			}
+			continue
+		}
+		if !c.handle(&req) {
+			return
+		}
+	}
+}
+
+// writeLoop writes queued messages and pings the client until the connection is closed
+func (c *wsConn) writeLoop() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	defer c.close()
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.out:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handle runs one client request and sends its reply, it returns false once the connection is closed
+func (c *wsConn) handle(req *wsRequest) bool {
+	switch req.Type {
+	case wsSubmit:
+		return c.submit(req)
+	case wsSubscribe:
+		if err := c.subscribe(req.TaskID); err != nil {
+			return c.sendError(req, err)
+		}
+		if !c.send(wsMessage{Type: wsSubscribed, ID: req.ID, TaskID: req.TaskID}) {
+			return false
+		}
+		c.deliverFinished(req.TaskID)
+		return true
+	case wsUnsubscribe:
+		c.lock.Lock()
+		delete(c.subscribed, req.TaskID)
+		c.lock.Unlock()
+		return c.send(wsMessage{Type: wsUnsubscribed, ID: req.ID, TaskID: req.TaskID})
+	case wsCancel:
+		if err := c.h.Scheduler.Cancel(req.TaskID); err != nil {
+			return c.sendError(req, err)
+		}
+		return c.send(wsMessage{Type: wsCancelled, ID: req.ID, TaskID: req.TaskID})
+	case wsPing:
+		return c.send(wsMessage{Type: wsPong, ID: req.ID})
+	default:
+		return c.sendError(req, fmt.Errorf("unknown message type %q", req.Type))
+	}
+}
+
+// sendError replies to a request that failed
+func (c *wsConn) sendError(req *wsRequest, err error) bool {
+	return c.send(wsMessage{Type: wsError, ID: req.ID, TaskID: req.TaskID, Error: err.Error()})
+}
+
+// submit adds a task and subscribes to its result
+//
+// The reply is queued before the lock is released so that it always comes before the result.
+func (c *wsConn) submit(req *wsRequest) bool {
+	if req.Task == nil {
+		return c.sendError(req, errors.New("task is required"))
+	}
+	fn, opts, err := req.Task.build()
+	if err != nil {
+		return c.sendError(req, fmt.Errorf("invalid task spec: %w", err))
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.subscribed) >= wsMaxSubscriptions {
+		return c.sendError(req, errTooManySubscriptions)
+	}
+	id, err := c.h.Scheduler.AddTask(fn, opts)
+	if err != nil {
+		return c.sendError(req, err)
+	}
+	c.subscribed[id] = struct{}{}
+	return c.send(wsMessage{Type: wsSubmitted, ID: req.ID, TaskID: id})
+}
+
+// subscribe starts waiting for the result of an existing task
+func (c *wsConn) subscribe(id string) error {
+	if _, ok := c.h.Scheduler.GetTask(id); !ok {
+		return scheduler.ErrTaskNotFound
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.subscribed) >= wsMaxSubscriptions {
+		return errTooManySubscriptions
+	}
+	c.subscribed[id] = struct{}{}
+	return nil
+}
+
+// deliver sends the result of a finished task if the connection still waits for it
+func (c *wsConn) deliver(task *models.Task) {
+	c.lock.Lock()
+	_, ok := c.subscribed[task.ID]
+	delete(c.subscribed, task.ID)
+	c.lock.Unlock()
+	if ok {
+		c.send(wsMessage{Type: wsResult, TaskID: task.ID, Task: taskResponse(task)})
+	}
+}
+
+// pushResults sends the results of subscribed tasks as they finish, starting with the events of sub
+func (c *wsConn) pushResults(sub *scheduler.Subscription) {
+	bus := c.h.Scheduler.Events()
+	defer func() { sub.Close() }()
+	var lastID uint64
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-bus.Done():
+			c.goAway()
+			return
+		case event, ok := <-sub.C:
+			if ok {
+				lastID = event.ID
+				c.handleEvent(event)
+				continue
+			}
+			select {
+			case <-bus.Done():
+				c.goAway()
+				return
+			default:
+			}
+			// dropped for falling behind: resume after the last event and look up results that left the replay buffer
+			var missed []scheduler.Event
+			sub, missed = bus.Subscribe(lastID)
+			for _, event := range missed {
+				lastID = event.ID
+				c.handleEvent(event)
+			}
+			c.catchUp()
+		}
+	}
+}
+
+// goAway tells the client the server is stopping and closes the connection
+func (c *wsConn) goAway() {
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	_ = c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+	c.close()
+}
+
+// handleEvent delivers the result carried by a final event
+func (c *wsConn) handleEvent(event scheduler.Event) {
+	switch event.Type {
+	case scheduler.EventSucceeded, scheduler.EventFailed, scheduler.EventCancelled:
+		c.deliver(event.Task)
+	}
+}
+
+// catchUp delivers the results of subscribed tasks that finished unnoticed
+func (c *wsConn) catchUp() {
+	c.lock.Lock()
+	ids := make([]string, 0, len(c.subscribed))
+	for id := range c.subscribed {
+		ids = append(ids, id)
+	}
+	c.lock.Unlock()
+	for _, id := range ids {
+		c.deliverFinished(id)
+	}
+}
+
+// deliverFinished delivers the result of a task if it has already finished
+func (c *wsConn) deliverFinished(id string) {
+	if task, ok := c.h.Scheduler.GetTask(id); ok && !task.FinishedAt.IsZero() {
+		c.deliver(task)
+	}
+}
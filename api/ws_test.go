@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/scheduler"
+	"github.com/gorilla/websocket"
+)
+
+// dialWS connects to the WebSocket endpoint of a test server
+func dialWS(t *testing.T, server *httptest.Server) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	return conn
+}
+
+// exchange sends a message and reads the next n messages
+func exchange(t *testing.T, conn *websocket.Conn, msg string, n int) []wsMessage {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	replies := make([]wsMessage, n)
+	for i := range replies {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		if err := conn.ReadJSON(&replies[i]); err != nil {
+			t.Fatalf("failed to read reply %d to %s: %v", i, msg, err)
+		}
+	}
+	return replies
+}
+
+func TestServeWS(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+	server := httptest.NewServer(http.HandlerFunc(h.ServeWS))
+	defer server.Close()
+	conn := dialWS(t, server)
+	defer conn.Close()
+
+	replies := exchange(t, conn, `{"type":"submit","id":"1","task":{"type":"http_status","url":"`+target.URL+`"}}`, 2)
+	if replies[0].Type != wsSubmitted || replies[0].ID != "1" || replies[0].TaskID == "" {
+		t.Fatalf("expected submitted reply, got %+v", replies[0])
+	}
+	if replies[1].Type != wsResult || replies[1].TaskID != replies[0].TaskID || replies[1].Task["status"] != "done" {
+		t.Fatalf("expected done result, got %+v", replies[1])
+	}
+
+	// a finished task is delivered right after the subscription
+	finished := replies[0].TaskID
+	replies = exchange(t, conn, `{"type":"subscribe","id":"2","task_id":"`+finished+`"}`, 2)
+	if replies[0].Type != wsSubscribed || replies[1].Type != wsResult || replies[1].TaskID != finished {
+		t.Fatalf("expected subscribed and result, got %+v", replies)
+	}
+
+	delayed, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "ok"}, nil
+	}, scheduler.TaskOptions{RunAt: time.Now().Add(time.Hour)})
+	_ = exchange(t, conn, `{"type":"subscribe","task_id":"`+delayed+`"}`, 1)
+	replies = exchange(t, conn, `{"type":"cancel","id":"3","task_id":"`+delayed+`"}`, 2)
+	if replies[0].Type != wsCancelled || replies[1].Type != wsResult || replies[1].Task["status"] != "cancelled" {
+		t.Fatalf("expected cancelled reply and result, got %+v", replies)
+	}
+
+	for _, msg := range []string{
+		`not json`,
+		`{"type":"unknown"}`,
+		`{"type":"submit"}`,
+		`{"type":"submit","task":{"type":"http_status"}}`,
+		`{"type":"subscribe","task_id":"missing"}`,
+		`{"type":"cancel","task_id":"` + delayed + `"}`,
+	} {
+		if reply := exchange(t, conn, msg, 1)[0]; reply.Type != wsError || reply.Error == "" {
+			t.Errorf("expected error for %s, got %+v", msg, reply)
+		}
+	}
+	if reply := exchange(t, conn, `{"type":"ping","id":"4"}`, 1)[0]; reply.Type != wsPong || reply.ID != "4" {
+		t.Errorf("expected pong, got %+v", reply)
+	}
+}
+
+func TestServeWS_Unsubscribe(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	h := NewHandler(s, NewLoggerForTest())
+	server := httptest.NewServer(http.HandlerFunc(h.ServeWS))
+	defer server.Close()
+	conn := dialWS(t, server)
+	defer conn.Close()
+
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "ok"}, nil
+	}, scheduler.TaskOptions{RunAt: time.Now().Add(50 * time.Millisecond)})
+	_ = exchange(t, conn, `{"type":"subscribe","task_id":"`+id+`"}`, 1)
+	if reply := exchange(t, conn, `{"type":"unsubscribe","task_id":"`+id+`"}`, 1)[0]; reply.Type != wsUnsubscribed {
+		t.Fatalf("expected unsubscribed, got %+v", reply)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if reply := exchange(t, conn, `{"type":"ping"}`, 1)[0]; reply.Type != wsPong {
+		t.Errorf("expected no result after unsubscribing, got %+v", reply)
+	}
+}
+
+func TestServeWS_Shutdown(t *testing.T) {
+	s := scheduler.NewScheduler(1)
+	h := NewHandler(s, NewLoggerForTest())
+	server := httptest.NewServer(http.HandlerFunc(h.ServeWS))
+	defer server.Close()
+	conn := dialWS(t, server)
+	defer conn.Close()
+
+	s.Stop()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected going away close, got %v", err)
+	}
+}
@@ -3,7 +3,9 @@ package config
 
 import (
 	"os"
+	"time"
 
+	"github.com/artnikel/taskscheduler/tasks"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,12 +21,62 @@ type LoggingConfig struct {
 
 // SchedulerConfig holds settings for task scheduling
 type SchedulerConfig struct {
-	MaxConcurrentTasks int `yaml:"max_concurrent_tasks"`
+	MaxConcurrentTasks int              `yaml:"max_concurrent_tasks"`
+	MaxQueueLength     int              `yaml:"max_queue_length"`
+	AgingInterval      time.Duration    `yaml:"aging_interval"`
+	Retry              RetryConfig      `yaml:"retry"`
+	Retention          RetentionConfig  `yaml:"retention"`
+	Schedules          []ScheduleConfig `yaml:"schedules"`
+}
+
+// RetentionConfig holds the limits for keeping finished tasks
+type RetentionConfig struct {
+	MaxAge        time.Duration  `yaml:"max_age"`
+	MaxCount      int            `yaml:"max_count"`
+	StatusLimits  map[string]int `yaml:"status_limits"`
+	SweepInterval time.Duration  `yaml:"sweep_interval"`
+}
+
+// ScheduleConfig holds a recurring task started with the service
+type ScheduleConfig struct {
+	tasks.Spec    `yaml:",inline"`
+	Interval      time.Duration `yaml:"interval"`
+	Cron          string        `yaml:"cron"`
+	Timezone      string        `yaml:"timezone"`
+	Jitter        time.Duration `yaml:"jitter"`
+	SkipIfRunning bool          `yaml:"skip_if_running"`
+	Priority      int           `yaml:"priority"`
+}
+
+// RetryConfig holds the default retry policy for failed tasks
+type RetryConfig struct {
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	Multiplier     float64       `yaml:"multiplier"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	Jitter         float64       `yaml:"jitter"`
+}
+
+// StorageConfig holds the task store settings
+type StorageConfig struct {
+	Backend          string        `yaml:"backend"`
+	DataDir          string        `yaml:"data_dir"`
+	SnapshotInterval time.Duration `yaml:"snapshot_interval"`
+	RunningOnRestart string        `yaml:"running_on_restart"`
+}
+
+// WebhookConfig holds the settings for the callbacks sent when tasks finish
+type WebhookConfig struct {
+	URL     string        `yaml:"url"`
+	Secret  string        `yaml:"secret"`
+	Timeout time.Duration `yaml:"timeout"`
+	Retry   RetryConfig   `yaml:"retry"`
 }
 
 // WorkerConfig holds settings for the background worker
 type WorkerConfig struct {
-	PingSites []string `yaml:"ping_sites"`
+	PingSites []string      `yaml:"ping_sites"`
+	Interval  time.Duration `yaml:"interval"`
 }
 
 // Config aggregates all service configurations
@@ -32,6 +84,8 @@ type Config struct {
 	Server    ServerConfig    `yaml:"server"`
 	Logging   LoggingConfig   `yaml:"logging"`
 	Scheduler SchedulerConfig `yaml:"scheduler"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Webhooks  WebhookConfig   `yaml:"webhooks"`
 	Worker    WorkerConfig    `yaml:"worker"`
 }
 
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,7 +15,42 @@ logging:
   path: "logs"
 scheduler:
   max_concurrent_tasks: 5
+  max_queue_length: 100
+  aging_interval: 30s
+  retry:
+    max_attempts: 3
+    initial_backoff: 200ms
+    multiplier: 2
+    max_backoff: 5s
+    jitter: 0.1
+  retention:
+    max_age: 1h
+    max_count: 10000
+    status_limits:
+      failed: 500
+    sweep_interval: 30s
+  schedules:
+    - type: http_status
+      url: "https://example.com"
+      method: HEAD
+      expected_status: ["2xx"]
+      cron: "0 9 * * MON-FRI"
+      timezone: "Europe/Berlin"
+      skip_if_running: true
+storage:
+  backend: file
+  data_dir: "data"
+  snapshot_interval: 10m
+  running_on_restart: requeue
+webhooks:
+  url: "https://hooks.example.com/tasks"
+  secret: "s3cret"
+  timeout: 5s
+  retry:
+    max_attempts: 4
+    initial_backoff: 1s
 worker:
+  interval: 30s
   ping_sites:
     - "google.com"
     - "yahoo.com"
@@ -34,7 +70,29 @@ worker:
 	if cfg.Scheduler.MaxConcurrentTasks != 5 {
 		t.Errorf("expected scheduler.max_concurrent_tasks 5, got %d", cfg.Scheduler.MaxConcurrentTasks)
 	}
+	if cfg.Storage.Backend != "file" || cfg.Storage.DataDir != "data" || cfg.Storage.SnapshotInterval != 10*time.Minute || cfg.Storage.RunningOnRestart != "requeue" {
+		t.Errorf("unexpected storage settings: %+v", cfg.Storage)
+	}
+	if cfg.Webhooks.URL != "https://hooks.example.com/tasks" || cfg.Webhooks.Secret != "s3cret" || cfg.Webhooks.Timeout != 5*time.Second || cfg.Webhooks.Retry.MaxAttempts != 4 {
+		t.Errorf("unexpected webhooks settings: %+v", cfg.Webhooks)
+	}
+	if cfg.Scheduler.MaxQueueLength != 100 || cfg.Scheduler.AgingInterval != 30*time.Second {
+		t.Errorf("unexpected scheduler queue settings: %+v", cfg.Scheduler)
+	}
+	if cfg.Scheduler.Retry.MaxAttempts != 3 || cfg.Scheduler.Retry.InitialBackoff != 200*time.Millisecond || cfg.Scheduler.Retry.MaxBackoff != 5*time.Second {
+		t.Errorf("unexpected scheduler.retry: %+v", cfg.Scheduler.Retry)
+	}
+	if cfg.Scheduler.Retention.MaxAge != time.Hour || cfg.Scheduler.Retention.MaxCount != 10000 || cfg.Scheduler.Retention.StatusLimits["failed"] != 500 {
+		t.Errorf("unexpected scheduler.retention: %+v", cfg.Scheduler.Retention)
+	}
+	if len(cfg.Scheduler.Schedules) != 1 || cfg.Scheduler.Schedules[0].URL != "https://example.com" || cfg.Scheduler.Schedules[0].Cron != "0 9 * * MON-FRI" ||
+		cfg.Scheduler.Schedules[0].Method != "HEAD" || len(cfg.Scheduler.Schedules[0].ExpectedStatus) != 1 {
+		t.Errorf("unexpected scheduler.schedules: %+v", cfg.Scheduler.Schedules)
+	}
 	if len(cfg.Worker.PingSites) != 2 || cfg.Worker.PingSites[0] != "google.com" || cfg.Worker.PingSites[1] != "yahoo.com" {
 		t.Errorf("unexpected worker.ping_sites: %+v", cfg.Worker.PingSites)
 	}
+	if cfg.Worker.Interval != 30*time.Second {
+		t.Errorf("expected worker.interval 30s, got %v", cfg.Worker.Interval)
+	}
 }
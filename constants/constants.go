@@ -6,16 +6,91 @@ import "time"
 // TaskStatus represents the state of a task
 type TaskStatus string
 
+// TaskType identifies what a task does
+type TaskType string
+
 const (
+	// StatusScheduled - Task waits for its run time before being queued
+	StatusScheduled TaskStatus = "scheduled"
 	// StatusPending - Task is queued for execution
 	StatusPending TaskStatus = "pending"
 	// StatusRunning - Task is currently executing
 	StatusRunning TaskStatus = "running"
+	// StatusRetrying - Task failed and waits for its next attempt
+	StatusRetrying TaskStatus = "retrying"
 	// StatusDone - Task completed successfully
 	StatusDone TaskStatus = "done"
 	// StatusFailed - Task execution failed
 	StatusFailed TaskStatus = "failed"
-	// TaskTimeout - Maximum allowed time for task execution
+	// StatusCancelled - Task was stopped before it could finish
+	StatusCancelled TaskStatus = "cancelled"
+	// TypePing - TCP ping of a host
+	TypePing TaskType = "ping"
+	// TypeICMP - ICMP echo ping of a host
+	TypeICMP TaskType = "icmp"
+	// TypeTLS - TLS certificate check of a host
+	TypeTLS TaskType = "tls"
+	// TypeHTTPStatus - HTTP status check of a URL
+	TypeHTTPStatus TaskType = "http_status"
+	// MinPriority - Lowest task priority, used by background traffic
+	MinPriority = 0
+	// MaxPriority - Highest task priority
+	MaxPriority = 9
+	// DefaultPriority - Priority of tasks submitted through the API without one
+	DefaultPriority = 5
+	// AgingInterval - Default time a queued task waits to gain one priority level
+	AgingInterval = 10 * time.Second
+	// MinScheduleInterval - Shortest interval of a recurring task from the API or the config
+	MinScheduleInterval = time.Second
+	// SweepInterval - Default time between two retention sweeps
+	SweepInterval = time.Minute
+	// SweepPageSize - Number of finished tasks a retention sweep reads from the store at a time
+	SweepPageSize = 500
+	// SnapshotInterval - Default time between two snapshots of the file task store
+	SnapshotInterval = 5 * time.Minute
+	// StorageMemory - Task store kept in memory only
+	StorageMemory = "memory"
+	// StorageFile - Task store kept in a write-ahead log and snapshots on disk
+	StorageFile = "file"
+	// StorageSQLite - Task store kept in a SQLite database
+	StorageSQLite = "sqlite"
+	// SQLiteFile - Name of the SQLite database in the data directory
+	SQLiteFile = "tasks.db"
+	// EventReplaySize - Number of recent task events kept for clients resuming an event stream
+	EventReplaySize = 1000
+	// EventHeartbeat - Interval of the keep-alive comments sent on an idle event stream
+	EventHeartbeat = 15 * time.Second
+	// WebhookTimeout - Default time limit of one webhook request
+	WebhookTimeout = 10 * time.Second
+	// WebhookMaxAttempts - Default number of attempts to deliver a webhook
+	WebhookMaxAttempts = 5
+	// WebhookInitialBackoff - Default delay before the first webhook retry
+	WebhookInitialBackoff = time.Second
+	// WebhookMaxBackoff - Default upper bound of the delay between webhook retries
+	WebhookMaxBackoff = time.Minute
+	// WebhookConcurrency - Number of webhook requests sent at the same time
+	WebhookConcurrency = 8
+	// PingPort - Default TCP port dialed by ping tasks
+	PingPort = 80
+	// PingInterval - Default time between the probes of a ping task
+	PingInterval = time.Second
+	// ICMPReplyTimeout - How long an ICMP echo probe waits for its reply
+	ICMPReplyTimeout = time.Second
+	// MaxPingCount - Largest number of probes of one ping task
+	MaxPingCount = 100
+	// TLSPort - Default TCP port of TLS certificate tasks
+	TLSPort = 443
+	// TLSMinDaysValid - Default number of days a certificate must stay valid for a TLS task to succeed
+	TLSMinDaysValid = 14
+	// HTTPMaxRedirects - Default number of redirects an HTTP task follows
+	HTTPMaxRedirects = 10
+	// MaxHTTPRedirects - Largest number of redirects an HTTP task may be set to follow
+	MaxHTTPRedirects = 30
+	// HTTPAssertBodyLimit - Largest part of a response body kept in memory for assertions
+	HTTPAssertBodyLimit = 10 << 20
+	// TombstoneTTL - How long the IDs of removed tasks are remembered as expired
+	TombstoneTTL = 24 * time.Hour
+	// TaskTimeout - Default maximum allowed time for task execution
 	TaskTimeout = 2 * time.Second
 	// ServerTimeout is read and write timeout of server config
 	ServerTimeout = 10 * time.Second
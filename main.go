@@ -3,10 +3,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"syscall"
 	"time"
@@ -15,8 +19,11 @@ import (
 	"github.com/artnikel/taskscheduler/config"
 	"github.com/artnikel/taskscheduler/constants"
 	"github.com/artnikel/taskscheduler/internal/logging"
+	"github.com/artnikel/taskscheduler/models"
 	"github.com/artnikel/taskscheduler/scheduler"
+	"github.com/artnikel/taskscheduler/store"
 	"github.com/artnikel/taskscheduler/tasks"
+	"github.com/artnikel/taskscheduler/webhook"
 )
 
 func main() {
@@ -30,26 +37,21 @@ func main() {
 		log.Fatalf("failed to init logger: %v", err)
 	}
 
-	sched := scheduler.NewScheduler(cfg.Scheduler.MaxConcurrentTasks)
-	handler := api.NewHandler(sched, logger)
-
-	mux := http.NewServeMux()
-
-	mux.HandleFunc("/tasks/ping", handler.CreatePingTask)
-	mux.HandleFunc("/tasks/", handler.GetTaskStatus)
-	mux.HandleFunc("/tasks/stats", handler.GetStats)
-	mux.HandleFunc("/tasks/http/status", handler.CreateStatusTask)
+	taskStore, closeStore, err := openStore(&cfg.Storage)
+	if err != nil {
+		logger.Error.Fatalf("failed to open task store: %v", err)
+	}
 
-	go func() { // worker for server load
-		ticker := time.NewTicker(time.Second)
-		defer ticker.Stop()
+	sched := newScheduler(cfg, taskStore)
+	dispatcher := newDispatcher(sched, &cfg.Webhooks)
+	if err := recoverTasks(sched, &cfg.Storage, logger); err != nil {
+		logger.Error.Fatalf("failed to recover tasks: %v", err)
+	}
+	mux := newMux(api.NewHandler(sched, logger))
 
-		for range ticker.C {
-			for _, site := range cfg.Worker.PingSites {
-				sched.AddTask(tasks.MakePingTask(site))
-			}
-		}
-	}()
+	if err := startSchedules(cfg, sched); err != nil {
+		logger.Error.Fatalf("failed to start schedules: %v", err)
+	}
 
 	server := &http.Server{
 		Addr:         ":" + strconv.Itoa(cfg.Server.Port),
@@ -57,6 +59,8 @@ func main() {
 		ReadTimeout:  constants.ServerTimeout,
 		WriteTimeout: constants.ServerTimeout,
 	}
+	// event streams never go idle, closing the bus ends them so that shutdown does not wait on them
+	server.RegisterOnShutdown(sched.Events().Close)
 
 	stopped := make(chan struct{})
 	go func() {
@@ -66,15 +70,201 @@ func main() {
 		ctx, cancel := context.WithTimeout(context.Background(), constants.ServerTimeout)
 		defer cancel()
 		if err := server.Shutdown(ctx); err != nil {
-			logger.Error.Fatalf("http server shutdown error %v", err)
+			logger.Error.Printf("http server shutdown error %v", err)
+		}
+		sched.Shutdown()
+		dispatcher.Close()
+		if err := closeStore(); err != nil {
+			logger.Error.Printf("failed to close task store: %v", err)
 		}
 		close(stopped)
 	}()
 
 	logger.Info.Printf("starting HTTP server on :%d\n", cfg.Server.Port)
-	if err := server.ListenAndServe(); err != nil {
+	// Shutdown makes ListenAndServe return at once, the shutdown goroutine then closes the scheduler and the store
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Error.Fatalf("http server not listening: %v", err)
 	}
 
 	<-stopped
 }
+
+// newScheduler creates the scheduler with the settings from the config
+func newScheduler(cfg *config.Config, taskStore store.TaskStore) *scheduler.Scheduler {
+	opts := []scheduler.Option{
+		scheduler.WithStore(taskStore),
+		scheduler.WithMaxQueue(cfg.Scheduler.MaxQueueLength),
+		scheduler.WithRetryPolicy(retryPolicy(&cfg.Scheduler.Retry)),
+	}
+	retention := cfg.Scheduler.Retention
+	statusLimits := make(map[constants.TaskStatus]int, len(retention.StatusLimits))
+	for status, limit := range retention.StatusLimits {
+		statusLimits[constants.TaskStatus(status)] = limit
+	}
+	opts = append(opts, scheduler.WithRetention(scheduler.RetentionPolicy{
+		MaxAge:        retention.MaxAge,
+		MaxCount:      retention.MaxCount,
+		StatusLimits:  statusLimits,
+		SweepInterval: retention.SweepInterval,
+	}))
+	if cfg.Scheduler.AgingInterval > 0 {
+		opts = append(opts, scheduler.WithAging(cfg.Scheduler.AgingInterval))
+	}
+	return scheduler.NewScheduler(cfg.Scheduler.MaxConcurrentTasks, opts...)
+}
+
+// retryPolicy converts a retry section of the config
+func retryPolicy(cfg *config.RetryConfig) scheduler.RetryPolicy {
+	return scheduler.RetryPolicy{
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: cfg.InitialBackoff,
+		Multiplier:     cfg.Multiplier,
+		MaxBackoff:     cfg.MaxBackoff,
+		Jitter:         cfg.Jitter,
+	}
+}
+
+// newDispatcher starts sending webhooks with the settings from the config
+func newDispatcher(sched *scheduler.Scheduler, cfg *config.WebhookConfig) *webhook.Dispatcher {
+	return webhook.NewDispatcher(sched, webhook.Config{
+		URL:     cfg.URL,
+		Secret:  cfg.Secret,
+		Timeout: cfg.Timeout,
+		Retry:   retryPolicy(&cfg.Retry),
+	})
+}
+
+// recoverTasks resumes the unfinished tasks found in the store with the policy from the config
+func recoverTasks(sched *scheduler.Scheduler, cfg *config.StorageConfig, logger *logging.Logger) error {
+	policy := scheduler.RecoveryPolicy(cfg.RunningOnRestart)
+	switch policy {
+	case "":
+		policy = scheduler.RecoverFail
+	case scheduler.RecoverFail, scheduler.RecoverRequeue:
+	default:
+		return fmt.Errorf("unknown storage.running_on_restart %q", policy)
+	}
+	recovered, err := sched.Recover(buildTask, policy)
+	if err != nil {
+		return err
+	}
+	if recovered > 0 {
+		logger.Info.Printf("recovered %d unfinished tasks\n", recovered)
+	}
+	return nil
+}
+
+// newMux routes the API endpoints to the handler
+func newMux(handler *api.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/tasks", handler.HandleTasks)
+	mux.HandleFunc("/tasks/ping", handler.CreatePingTask)
+	mux.HandleFunc("/tasks/icmp", handler.CreateICMPTask)
+	mux.HandleFunc("/tasks/tls", handler.CreateTLSTask)
+	mux.HandleFunc("/tasks/", handler.HandleTask)
+	mux.HandleFunc("/tasks/stats", handler.GetStats)
+	mux.HandleFunc("/tasks/http/status", handler.CreateStatusTask)
+	mux.HandleFunc("/tasks/batch", handler.CreateBatch)
+	mux.HandleFunc("/batches/", handler.GetBatch)
+	mux.HandleFunc("/events", handler.StreamEvents)
+	mux.HandleFunc("/ws", handler.ServeWS)
+
+	mux.HandleFunc("/schedules", handler.HandleSchedules)
+	mux.HandleFunc("/schedules/", handler.HandleSchedule)
+	return mux
+}
+
+// openStore opens the task store selected in the config and returns a function that closes it
+func openStore(cfg *config.StorageConfig) (store.TaskStore, func() error, error) {
+	switch cfg.Backend {
+	case "", constants.StorageMemory:
+		return store.NewMemoryStore(), func() error { return nil }, nil
+	case constants.StorageFile:
+		if cfg.DataDir == "" {
+			return nil, nil, fmt.Errorf("storage.data_dir is required for the %s backend", cfg.Backend)
+		}
+		fileStore, err := store.OpenFileStore(cfg.DataDir, cfg.SnapshotInterval)
+		if err != nil {
+			return nil, nil, err
+		}
+		return fileStore, fileStore.Close, nil
+	case constants.StorageSQLite:
+		if cfg.DataDir == "" {
+			return nil, nil, fmt.Errorf("storage.data_dir is required for the %s backend", cfg.Backend)
+		}
+		if err := os.MkdirAll(cfg.DataDir, constants.DirPerm); err != nil {
+			return nil, nil, err
+		}
+		sqliteStore, err := store.OpenSQLiteStore(filepath.Join(cfg.DataDir, constants.SQLiteFile))
+		if err != nil {
+			return nil, nil, err
+		}
+		return sqliteStore, sqliteStore.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// buildTask rebuilds a recovered task from the spec stored with it
+func buildTask(task *models.Task) (scheduler.TaskFunc, error) {
+	var spec tasks.Spec
+	if err := json.Unmarshal(task.Spec, &spec); err != nil {
+		return nil, fmt.Errorf("invalid stored spec: %w", err)
+	}
+	return spec.Build()
+}
+
+// encodeSpec returns the stored form of a task spec
+func encodeSpec(spec tasks.Spec) json.RawMessage {
+	data, _ := json.Marshal(spec)
+	return data
+}
+
+// startSchedules starts the schedules from the config and the background ping worker
+func startSchedules(cfg *config.Config, sched *scheduler.Scheduler) error {
+	for _, sc := range cfg.Scheduler.Schedules {
+		if sc.Interval > 0 && sc.Interval < constants.MinScheduleInterval {
+			return fmt.Errorf("invalid schedule in config: interval must be at least %v", constants.MinScheduleInterval)
+		}
+		fn, err := sc.Build()
+		if err != nil {
+			return fmt.Errorf("invalid schedule in config: %w", err)
+		}
+		_, err = sched.AddSchedule(scheduler.ScheduleSpec{
+			Interval:      sc.Interval,
+			Cron:          sc.Cron,
+			Timezone:      sc.Timezone,
+			Jitter:        sc.Jitter,
+			SkipIfRunning: sc.SkipIfRunning,
+			Task:          fn,
+			Options:       scheduler.TaskOptions{Type: sc.Type, Priority: sc.Priority, Timeout: sc.MinTimeout(), Spec: encodeSpec(sc.Spec)},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	interval := cfg.Worker.Interval
+	if interval <= 0 {
+		interval = time.Second
+	} else if interval < constants.MinScheduleInterval {
+		return fmt.Errorf("worker.interval must be at least %v", constants.MinScheduleInterval)
+	}
+	for _, site := range cfg.Worker.PingSites { // worker for server load
+		_, err := sched.AddSchedule(scheduler.ScheduleSpec{
+			Interval:      interval,
+			SkipIfRunning: true,
+			Task:          tasks.MakePingTask(site),
+			Options: scheduler.TaskOptions{
+				Type:     constants.TypePing,
+				Priority: constants.MinPriority,
+				Spec:     encodeSpec(tasks.Spec{Type: constants.TypePing, Address: site}),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to schedule ping of %s: %w", site, err)
+		}
+	}
+	return nil
+}
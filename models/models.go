@@ -1,12 +1,137 @@
 // Package models provides the data models used in the application
 package models
 
-import "github.com/artnikel/taskscheduler/constants"
+import (
+	"encoding/json"
+	"maps"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+)
 
 // Task entity
 type Task struct {
-	ID     string
-	Status constants.TaskStatus
-	Result string
-	Err    error
+	ID       string
+	Type     constants.TaskType
+	Status   constants.TaskStatus
+	Priority int
+	Result   *Result
+	Err      error
+	// CreatedAt is the time the task was submitted
+	CreatedAt time.Time
+	// StartedAt is the time the first attempt started
+	StartedAt time.Time
+	// FinishedAt is the time the task reached a final status
+	FinishedAt time.Time
+	// QueueWait is the time spent in the run queue over all attempts
+	QueueWait time.Duration
+	// RunDuration is the time spent running over all attempts
+	RunDuration time.Duration
+	// RunAt is the time a delayed task becomes due
+	RunAt time.Time
+	// ScheduleID links a task to the recurring schedule that created it
+	ScheduleID string
+	// BatchID links a task to the batch it was submitted with
+	BatchID string
+	// Attempts counts how many times the task has been started
+	Attempts int
+	// AttemptErrors holds the error of every failed attempt in order
+	AttemptErrors []string
+	// Tags are free-form labels given at submission
+	Tags []string
+	// Timeout limits a single run of the task
+	Timeout time.Duration
+	// Spec is the encoded task description used to rebuild the task after a restart
+	Spec json.RawMessage
+	// CallbackURL receives the outcome of the task when it is done or failed
+	CallbackURL string
+	// Deliveries logs every attempt to send the outcome to a callback URL
+	Deliveries []Delivery
+}
+
+// Clone returns a copy of the task that shares no slices with the original
+func (t *Task) Clone() *Task {
+	c := *t
+	c.AttemptErrors = append([]string(nil), t.AttemptErrors...)
+	c.Tags = append([]string(nil), t.Tags...)
+	c.Spec = append(json.RawMessage(nil), t.Spec...)
+	c.Deliveries = append([]Delivery(nil), t.Deliveries...)
+	c.Result = t.Result.Clone()
+	return &c
+}
+
+// Result is the structured outcome of a task run, failed runs have one when they got far enough to measure something
+type Result struct {
+	// Summary is a human-readable description of the outcome
+	Summary string `json:"summary"`
+	// LatencyMS is the time the check took in milliseconds
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+	// StatusCode is the HTTP status code of HTTP checks
+	StatusCode int `json:"status_code,omitempty"`
+	// Bytes is the size of the response body of HTTP checks
+	Bytes int64 `json:"bytes,omitempty"`
+	// ResolvedIP is the address the target host resolved to
+	ResolvedIP string `json:"resolved_ip,omitempty"`
+	// Metrics holds any other values a task reports
+	Metrics map[string]any `json:"metrics,omitempty"`
+	// Assertions holds the outcome of every response check of HTTP checks
+	Assertions []AssertionResult `json:"assertions,omitempty"`
+}
+
+// AssertionResult is the outcome of one check on the response of an HTTP check
+type AssertionResult struct {
+	// Check names the check and its arguments, e.g. body_contains "ok"
+	Check  string `json:"check"`
+	Passed bool   `json:"passed"`
+	// Error is why the check failed, empty when it passed
+	Error string `json:"error,omitempty"`
+}
+
+// Clone returns a copy of the result that shares no map with the original, nil stays nil
+func (r *Result) Clone() *Result {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	c.Metrics = maps.Clone(r.Metrics)
+	c.Assertions = append([]AssertionResult(nil), r.Assertions...)
+	return &c
+}
+
+// Milliseconds converts a duration to fractional milliseconds for LatencyMS
+func Milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// Delivery is one attempt to send the outcome of a task to its callback URL
+type Delivery struct {
+	// Attempt counts the attempts of the delivery from 1
+	Attempt int `json:"attempt"`
+	// Event is the task event that was sent
+	Event string `json:"event"`
+	URL   string `json:"url"`
+	// SentAt is the time the request was sent
+	SentAt time.Time `json:"sent_at"`
+	// DurationMS is the time until the response or error in milliseconds
+	DurationMS float64 `json:"duration_ms"`
+	// StatusCode is the HTTP status code of the response, zero when there was none
+	StatusCode int `json:"status_code,omitempty"`
+	// Error is why the attempt failed, empty on success
+	Error string `json:"error,omitempty"`
+}
+
+// Schedule entity of a recurring task
+type Schedule struct {
+	ID            string
+	Type          constants.TaskType
+	Interval      time.Duration
+	Cron          string
+	Timezone      string
+	Jitter        time.Duration
+	SkipIfRunning bool
+	CreatedAt     time.Time
+	NextRun       time.Time
+	LastTaskID    string
+	Runs          int
+	Skipped       int
 }
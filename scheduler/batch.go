@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"errors"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/store"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrBatchNotFound is returned when no task belongs to the given batch
+	ErrBatchNotFound = errors.New("batch not found")
+	// ErrEmptyBatch is returned when a batch has no tasks
+	ErrEmptyBatch = errors.New("batch has no tasks")
+)
+
+// BatchItem is one task of a batch
+type BatchItem struct {
+	Task    TaskFunc
+	Options TaskOptions
+}
+
+// Batch is the progress of the tasks submitted together
+type Batch struct {
+	ID string
+	// Tasks are the tasks of the batch ordered by creation time
+	Tasks []*models.Task
+	// Counts is the number of tasks in every status
+	Counts map[constants.TaskStatus]int
+	// Finished is the number of tasks in a final status
+	Finished int
+}
+
+// AddBatch submits several tasks at once and returns the batch ID and the task IDs in item order
+//
+// Either all tasks are queued or none is: the batch is rejected as a whole
+// when the scheduler is stopped, when its immediate tasks do not fit in the
+// run queue, or when a task cannot be stored.
+func (s *Scheduler) AddBatch(items []BatchItem) (string, []string, error) {
+	if len(items) == 0 {
+		return "", nil, ErrEmptyBatch
+	}
+	batchID := uuid.NewString()
+	entries := make([]*taskEntry, 0, len(items))
+	immediate := 0
+	for _, item := range items {
+		entry := s.newEntry(item.Task, item.Options)
+		entry.task.BatchID = batchID
+		if entry.due.IsZero() {
+			immediate++
+		}
+		entries = append(entries, entry)
+	}
+	reject := func(err error) (string, []string, error) {
+		for _, entry := range entries {
+			entry.cancel()
+		}
+		return "", nil, err
+	}
+
+	s.taskLock.Lock()
+	defer s.taskLock.Unlock()
+
+	select {
+	case <-s.done:
+		return reject(ErrStopped)
+	default:
+	}
+	if s.maxQueue > 0 && s.queue.Len()+immediate > s.maxQueue {
+		return reject(ErrQueueFull)
+	}
+	for i, entry := range entries {
+		if err := s.store.Put(entry.task); err != nil {
+			for _, stored := range entries[:i] {
+				_ = s.store.Delete(stored.task.ID)
+			}
+			return reject(err)
+		}
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		s.admit(entry)
+		ids = append(ids, entry.task.ID)
+	}
+	return batchID, ids, nil
+}
+
+// GetBatch returns the progress of a batch, tasks removed by the retention policy are not included
+func (s *Scheduler) GetBatch(id string) (*Batch, error) {
+	tasks, err := s.store.List(store.Filter{BatchID: id})
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, ErrBatchNotFound
+	}
+	batch := &Batch{ID: id, Tasks: tasks, Counts: make(map[constants.TaskStatus]int)}
+	for _, task := range tasks {
+		batch.Counts[task.Status]++
+		if !isActive(task.Status) {
+			batch.Finished++
+		}
+	}
+	return batch, nil
+}
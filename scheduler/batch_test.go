@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+func TestAddBatch(t *testing.T) {
+	s := NewScheduler(2)
+	defer s.Stop()
+
+	ok := func(context.Context) (*models.Result, error) { return &models.Result{Summary: "ok"}, nil }
+	fail := func(context.Context) (*models.Result, error) { return nil, errors.New("boom") }
+	batchID, ids, err := s.AddBatch([]BatchItem{
+		{Task: ok},
+		{Task: fail},
+		{Task: ok, Options: TaskOptions{RunAt: time.Now().Add(time.Hour)}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != 3 {
+		t.Fatalf("expected 3 task IDs, got %d", len(ids))
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	batch, err := s.GetBatch(batchID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if batch.Finished != 2 || batch.Counts[constants.StatusDone] != 1 ||
+		batch.Counts[constants.StatusFailed] != 1 || batch.Counts[constants.StatusScheduled] != 1 {
+		t.Errorf("unexpected batch progress: %+v", batch)
+	}
+	if _, err := s.GetBatch("missing"); !errors.Is(err, ErrBatchNotFound) {
+		t.Errorf("expected ErrBatchNotFound, got %v", err)
+	}
+	if _, _, err := s.AddBatch(nil); !errors.Is(err, ErrEmptyBatch) {
+		t.Errorf("expected ErrEmptyBatch, got %v", err)
+	}
+}
+
+func TestAddBatch_QueueFullRejectsAll(t *testing.T) {
+	s := NewScheduler(1, WithMaxQueue(2))
+	defer s.Stop()
+
+	release := make(chan struct{})
+	defer close(release)
+	block := func(context.Context) (*models.Result, error) {
+		<-release
+		return &models.Result{Summary: "ok"}, nil
+	}
+	_, _ = s.AddTask(block, TaskOptions{})
+	time.Sleep(20 * time.Millisecond)
+	_, _ = s.AddTask(block, TaskOptions{})
+
+	_, _, err := s.AddBatch([]BatchItem{{Task: block}, {Task: block}})
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if stats := s.GetStats(); stats[constants.StatusPending] != 1 {
+		t.Errorf("expected no task of the rejected batch to be queued, got %d pending", stats[constants.StatusPending])
+	}
+}
@@ -0,0 +1,104 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/models"
+)
+
+func TestParseCron(t *testing.T) {
+	valid := []struct{ expr, timezone string }{
+		{"*/5 * * * *", ""},
+		{"0 9 * * MON-FRI", "Europe/Berlin"},
+		{"30 */5 * * * *", ""},
+		{"@hourly", ""},
+		{"@daily", "UTC"},
+	}
+	for _, tc := range valid {
+		if _, err := ParseCron(tc.expr, tc.timezone); err != nil {
+			t.Errorf("%q in %q: unexpected error: %v", tc.expr, tc.timezone, err)
+		}
+	}
+
+	invalid := []struct{ expr, timezone string }{
+		{"* * *", ""},
+		{"61 * * * *", ""},
+		{"@sometimes", ""},
+		{"* * * * *", "Mars/Olympus"},
+	}
+	for _, tc := range invalid {
+		if _, err := ParseCron(tc.expr, tc.timezone); err == nil {
+			t.Errorf("%q in %q: expected error, got nil", tc.expr, tc.timezone)
+		}
+	}
+}
+
+func TestNextRuns_Cron(t *testing.T) {
+	s := NewScheduler(1)
+
+	id, err := s.AddSchedule(ScheduleSpec{
+		Cron:     "0 9 * * MON-FRI",
+		Timezone: "America/New_York",
+		Task:     func(context.Context) (*models.Result, error) { return &models.Result{Summary: "ok"}, nil },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = s.StopSchedule(id) }()
+
+	runs, err := s.NextRuns(id, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runs) != 10 {
+		t.Fatalf("expected 10 runs, got %d", len(runs))
+	}
+	loc, _ := time.LoadLocation("America/New_York")
+	for i, run := range runs {
+		local := run.In(loc)
+		if local.Hour() != 9 || local.Minute() != 0 || local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+			t.Errorf("unexpected fire time %v", local)
+		}
+		if i > 0 && !run.After(runs[i-1]) {
+			t.Errorf("fire times are not increasing: %v", runs)
+		}
+	}
+}
+
+func TestNextRuns_Interval(t *testing.T) {
+	s := NewScheduler(1)
+
+	id, _ := s.AddSchedule(ScheduleSpec{
+		Interval: time.Hour,
+		Task:     func(context.Context) (*models.Result, error) { return &models.Result{Summary: "ok"}, nil },
+	})
+	defer func() { _ = s.StopSchedule(id) }()
+
+	runs, _ := s.NextRuns(id, 3)
+	if len(runs) != 3 || runs[2].Sub(runs[0]) != 2*time.Hour {
+		t.Errorf("unexpected fire times: %v", runs)
+	}
+	if _, err := s.NextRuns("nonexistent", 3); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("expected ErrScheduleNotFound, got %v", err)
+	}
+}
+
+func TestAddSchedule_InvalidCron(t *testing.T) {
+	s := NewScheduler(1)
+	task := func(context.Context) (*models.Result, error) { return &models.Result{Summary: "ok"}, nil }
+
+	specs := []ScheduleSpec{
+		{Cron: "not a cron", Task: task},
+		{Cron: "@hourly", Interval: time.Second, Task: task},
+		{Interval: time.Second, Timezone: "UTC", Task: task},
+		{Cron: "0 0 30 2 *", Task: task},
+	}
+	for _, spec := range specs {
+		if _, err := s.AddSchedule(spec); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("spec %+v: expected ErrInvalidSchedule, got %v", spec, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"container/heap"
+	"time"
+)
+
+// idleWait is how long the dispatcher sleeps when no task is delayed
+const idleWait = time.Hour
+
+// delayQueue is a min-heap of delayed and retrying tasks ordered by due time
+type delayQueue []*taskEntry
+
+func (q delayQueue) Len() int { return len(q) }
+
+func (q delayQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+
+func (q delayQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *delayQueue) Push(x any) {
+	entry, _ := x.(*taskEntry)
+	entry.index = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *delayQueue) Pop() any {
+	old := *q
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	entry.index = -1
+	*q = old[:n-1]
+	return entry
+}
+
+// pushDelayed adds a task to the timer heap, the caller must hold taskLock
+func (s *Scheduler) pushDelayed(entry *taskEntry) {
+	heap.Push(&s.delayed, entry)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// removeDelayed drops a task from the timer heap, the caller must hold taskLock
+func (s *Scheduler) removeDelayed(entry *taskEntry) {
+	if entry.index >= 0 && entry.index < s.delayed.Len() && s.delayed[entry.index] == entry {
+		heap.Remove(&s.delayed, entry.index)
+	}
+}
+
+// dispatchDelayed moves due tasks from the timer heap to the run queue
+func (s *Scheduler) dispatchDelayed() {
+	timer := time.NewTimer(idleWait)
+	defer timer.Stop()
+
+	for {
+		now := time.Now()
+		wait := idleWait
+
+		s.taskLock.Lock()
+		for s.delayed.Len() > 0 {
+			next := s.delayed[0]
+			if next.due.After(now) {
+				wait = next.due.Sub(now)
+				break
+			}
+			heap.Pop(&s.delayed)
+			s.enqueue(next)
+		}
+		s.taskLock.Unlock()
+
+		timer.Reset(wait)
+		select {
+		case <-s.done:
+			return
+		case <-timer.C:
+		case <-s.wake:
+		}
+	}
+}
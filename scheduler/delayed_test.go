@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+func TestAddTask_RunAt(t *testing.T) {
+	s := NewScheduler(1)
+
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{RunAt: time.Now().Add(60 * time.Millisecond)})
+
+	time.Sleep(20 * time.Millisecond)
+	task, _ := s.GetTask(id)
+	if task.Status != constants.StatusScheduled {
+		t.Errorf("expected status %s, got %s", constants.StatusScheduled, task.Status)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	task, _ = s.GetTask(id)
+	if task.Status != constants.StatusDone {
+		t.Errorf("expected status %s, got %s", constants.StatusDone, task.Status)
+	}
+}
+
+func TestAddTask_RunAtOrder(t *testing.T) {
+	s := NewScheduler(1)
+
+	order := make(chan string, 3)
+	for _, tc := range []struct {
+		name  string
+		delay time.Duration
+	}{{"third", 90 * time.Millisecond}, {"first", 30 * time.Millisecond}, {"second", 60 * time.Millisecond}} {
+		name := tc.name
+		_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+			order <- name
+			return &models.Result{Summary: name}, nil
+		}, TaskOptions{RunAt: time.Now().Add(tc.delay)})
+	}
+
+	for _, want := range []string{"first", "second", "third"} {
+		select {
+		case got := <-order:
+			if got != want {
+				t.Fatalf("expected %s, got %s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("delayed task did not run")
+		}
+	}
+}
+
+func TestCancel_Scheduled(t *testing.T) {
+	s := NewScheduler(1)
+
+	ran := make(chan struct{}, 1)
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		ran <- struct{}{}
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{RunAt: time.Now().Add(30 * time.Millisecond)})
+
+	if err := s.Cancel(id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case <-ran:
+		t.Error("cancelled scheduled task should not run")
+	case <-time.After(60 * time.Millisecond):
+	}
+	task, _ := s.GetTask(id)
+	if task.Status != constants.StatusCancelled {
+		t.Errorf("expected status %s, got %s", constants.StatusCancelled, task.Status)
+	}
+}
@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/artnikel/taskscheduler/models"
+)
+
+// DurationStats summarizes how long the tasks finished since the scheduler started waited and ran
+type DurationStats struct {
+	Finished       int
+	AvgQueueWait   time.Duration
+	MaxQueueWait   time.Duration
+	AvgRunDuration time.Duration
+	MaxRunDuration time.Duration
+}
+
+// durationTotals accumulates the durations of finished tasks
+type durationTotals struct {
+	finished       int
+	queueWait      time.Duration
+	maxQueueWait   time.Duration
+	runDuration    time.Duration
+	maxRunDuration time.Duration
+}
+
+// add counts a task that has just finished
+func (d *durationTotals) add(task *models.Task) {
+	d.finished++
+	d.queueWait += task.QueueWait
+	d.maxQueueWait = max(d.maxQueueWait, task.QueueWait)
+	d.runDuration += task.RunDuration
+	d.maxRunDuration = max(d.maxRunDuration, task.RunDuration)
+}
+
+// Durations returns the queue wait and run duration of the tasks finished so far
+func (s *Scheduler) Durations() DurationStats {
+	s.taskLock.RLock()
+	defer s.taskLock.RUnlock()
+
+	d := s.durations
+	stats := DurationStats{
+		Finished:       d.finished,
+		MaxQueueWait:   d.maxQueueWait,
+		MaxRunDuration: d.maxRunDuration,
+	}
+	if d.finished > 0 {
+		stats.AvgQueueWait = d.queueWait / time.Duration(d.finished)
+		stats.AvgRunDuration = d.runDuration / time.Duration(d.finished)
+	}
+	return stats
+}
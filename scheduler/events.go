@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+// EventType is the kind of a task lifecycle event
+type EventType string
+
+const (
+	// EventCreated is published when a task is accepted
+	EventCreated EventType = "created"
+	// EventStarted is published when an attempt of a task starts running
+	EventStarted EventType = "started"
+	// EventRetried is published when a failed attempt is scheduled to run again
+	EventRetried EventType = "retried"
+	// EventSucceeded is published when a task is done
+	EventSucceeded EventType = "succeeded"
+	// EventFailed is published when a task has failed for good
+	EventFailed EventType = "failed"
+	// EventCancelled is published when a task is cancelled
+	EventCancelled EventType = "cancelled"
+)
+
+// finishEvent returns the event of a final status
+func finishEvent(status constants.TaskStatus) EventType {
+	switch status {
+	case constants.StatusDone:
+		return EventSucceeded
+	case constants.StatusCancelled:
+		return EventCancelled
+	default:
+		return EventFailed
+	}
+}
+
+// subscriberBuffer is the number of events a subscriber may fall behind before it is dropped
+const subscriberBuffer = 256
+
+// Event is a change in the lifecycle of a task
+type Event struct {
+	// ID increases by one with every event published by the bus
+	ID   uint64
+	Type EventType
+	Time time.Time
+	// Task is a copy of the task at the time of the event
+	Task *models.Task
+}
+
+// EventBus fans out task events to subscribers and keeps the latest ones for replay
+type EventBus struct {
+	lock        sync.Mutex
+	lastID      uint64
+	replay      []Event
+	start       int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+	done        chan struct{}
+}
+
+// Subscription receives the events published after it was created
+//
+// The channel is closed when the subscriber falls too far behind or the bus is
+// closed, a dropped subscriber can resume from the last event it received.
+type Subscription struct {
+	C   <-chan Event
+	ch  chan Event
+	bus *EventBus
+}
+
+// NewEventBus creates an event bus that keeps the last size events for replay
+func NewEventBus(size int) *EventBus {
+	if size < 1 {
+		size = 1
+	}
+	return &EventBus{
+		replay:      make([]Event, 0, size),
+		subscribers: make(map[*Subscription]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Events returns the bus of the task lifecycle events
+func (s *Scheduler) Events() *EventBus {
+	return s.events
+}
+
+// Subscribe starts receiving events and returns the buffered events published after lastID
+//
+// The replayed events start with the oldest one still buffered when lastID is
+// too old. The subscription of a closed bus has a closed channel.
+func (b *EventBus) Subscribe(lastID uint64) (*Subscription, []Event) {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, bus: b}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var missed []Event
+	for i := range b.replay {
+		event := b.replay[(b.start+i)%len(b.replay)]
+		if event.ID > lastID {
+			missed = append(missed, event)
+		}
+	}
+	if b.closed {
+		close(ch)
+		return sub, missed
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub, missed
+}
+
+// Close stops the subscription and closes its channel
+func (sub *Subscription) Close() {
+	sub.bus.lock.Lock()
+	defer sub.bus.lock.Unlock()
+	sub.bus.drop(sub)
+}
+
+// drop removes a subscriber and closes its channel, the caller must hold lock
+func (b *EventBus) drop(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// publish records an event for a copy of the task and sends it to every subscriber
+func (b *EventBus) publish(eventType EventType, task *models.Task) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return
+	}
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Time: time.Now(), Task: task.Clone()}
+	if len(b.replay) < cap(b.replay) {
+		b.replay = append(b.replay, event)
+	} else {
+		b.replay[b.start] = event
+		b.start = (b.start + 1) % len(b.replay)
+	}
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			b.drop(sub)
+		}
+	}
+}
+
+// Close ends every subscription, events published afterwards are discarded
+func (b *EventBus) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	close(b.done)
+	for sub := range b.subscribers {
+		b.drop(sub)
+	}
+}
+
+// Done returns a channel that is closed when the bus is closed
+func (b *EventBus) Done() <-chan struct{} {
+	return b.done
+}
@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/models"
+)
+
+// collect reads events from a subscription until n events arrive or the timeout passes
+func collect(t *testing.T, sub *Subscription, n int) []Event {
+	t.Helper()
+	var events []Event
+	timeout := time.After(time.Second)
+	for len(events) < n {
+		select {
+		case event, ok := <-sub.C:
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		case <-timeout:
+			return events
+		}
+	}
+	return events
+}
+
+func TestEvents_Lifecycle(t *testing.T) {
+	s := NewScheduler(1, WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: 10 * time.Millisecond, Multiplier: 1}))
+	defer s.Stop()
+	sub, _ := s.Events().Subscribe(0)
+	defer sub.Close()
+
+	calls := 0
+	failing, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("first attempt fails")
+		}
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
+	events := collect(t, sub, 5)
+
+	want := []EventType{EventCreated, EventStarted, EventRetried, EventStarted, EventSucceeded}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %d", len(want), len(events))
+	}
+	for i, event := range events {
+		if event.Type != want[i] || event.Task.ID != failing {
+			t.Errorf("event %d: expected %s of %s, got %s of %s", i, want[i], failing, event.Type, event.Task.ID)
+		}
+		if i > 0 && event.ID != events[i-1].ID+1 {
+			t.Errorf("event %d: expected ID %d, got %d", i, events[i-1].ID+1, event.ID)
+		}
+	}
+	if events[4].Task.Result == nil || events[4].Task.Result.Summary != "ok" {
+		t.Errorf("expected the succeeded event to carry the result, got %+v", events[4].Task.Result)
+	}
+
+	cancelled, _ := s.AddTask(func(ctx context.Context) (*models.Result, error) {
+		return nil, nil
+	}, TaskOptions{RunAt: time.Now().Add(time.Hour)})
+	_ = s.Cancel(cancelled)
+	events = collect(t, sub, 2)
+	if len(events) != 2 || events[0].Type != EventCreated || events[1].Type != EventCancelled {
+		t.Fatalf("expected created and cancelled events, got %+v", events)
+	}
+}
+
+func TestEventBus_Replay(t *testing.T) {
+	bus := NewEventBus(3)
+	for i := range 5 {
+		bus.publish(EventCreated, &models.Task{ID: string(rune('a' + i))})
+	}
+
+	tests := []struct {
+		name   string
+		lastID uint64
+		want   []uint64
+	}{
+		{"from start", 0, []uint64{3, 4, 5}},
+		{"expired ID", 1, []uint64{3, 4, 5}},
+		{"resume", 3, []uint64{4, 5}},
+		{"up to date", 5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, missed := bus.Subscribe(tt.lastID)
+			defer sub.Close()
+			if len(missed) != len(tt.want) {
+				t.Fatalf("expected %d events, got %d", len(tt.want), len(missed))
+			}
+			for i, event := range missed {
+				if event.ID != tt.want[i] {
+					t.Errorf("expected event %d at %d, got %d", tt.want[i], i, event.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestEventBus_SlowSubscriber(t *testing.T) {
+	bus := NewEventBus(10)
+	slow, _ := bus.Subscribe(0)
+	for range subscriberBuffer + 1 {
+		bus.publish(EventCreated, &models.Task{ID: "task"})
+	}
+	received := 0
+	for range slow.C {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("expected %d events before the drop, got %d", subscriberBuffer, received)
+	}
+
+	sub, _ := bus.Subscribe(0)
+	bus.Close()
+	if _, ok := <-sub.C; ok {
+		t.Error("expected closing the bus to end subscriptions")
+	}
+	sub.Close()
+}
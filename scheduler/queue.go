@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+)
+
+// runQueue is a heap of tasks waiting for a free worker
+type runQueue []*taskEntry
+
+func (q runQueue) Len() int { return len(q) }
+
+func (q runQueue) Less(i, j int) bool {
+	if q[i].rank != q[j].rank {
+		return q[i].rank > q[j].rank
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q runQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *runQueue) Push(x any) {
+	entry, _ := x.(*taskEntry)
+	entry.index = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *runQueue) Pop() any {
+	old := *q
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	entry.index = -1
+	*q = old[:n-1]
+	return entry
+}
+
+// enqueue adds a task to the run queue and wakes a worker, the caller must hold taskLock
+//
+// With aging the effective priority of a queued task is priority + waited/aging.
+// Comparing two tasks that way does not depend on the current time, so the
+// rank priority*aging - enqueuedAt is fixed at enqueue and keeps the heap valid.
+func (s *Scheduler) enqueue(entry *taskEntry) {
+	s.seq++
+	entry.seq = s.seq
+	entry.rank = int64(entry.opts.Priority)
+	if s.aging > 0 {
+		entry.rank = int64(entry.opts.Priority)*int64(s.aging) - time.Now().UnixNano()
+	}
+	entry.task.Status = constants.StatusPending
+	entry.enqueuedAt = time.Now()
+	s.save(entry.task)
+	heap.Push(&s.queue, entry)
+	s.queueReady.Signal()
+}
+
+// dequeue removes a pending task from the run queue, the caller must hold taskLock
+func (s *Scheduler) dequeue(entry *taskEntry) {
+	if entry.index >= 0 && entry.index < s.queue.Len() && s.queue[entry.index] == entry {
+		heap.Remove(&s.queue, entry.index)
+	}
+}
+
+// worker runs queued tasks one at a time until the scheduler stops
+func (s *Scheduler) worker() {
+	for {
+		entry, ok := s.next()
+		if !ok {
+			return
+		}
+		runCtx, cancel := context.WithTimeout(entry.ctx, entry.opts.Timeout)
+		result, err := entry.fn(runCtx)
+		cancel()
+		s.finishAttempt(entry, result, err)
+	}
+}
+
+// next blocks until a task is queued and marks it as running, it reports false once the scheduler stops
+func (s *Scheduler) next() (*taskEntry, bool) {
+	s.taskLock.Lock()
+	defer s.taskLock.Unlock()
+
+	for s.queue.Len() == 0 {
+		select {
+		case <-s.done:
+			return nil, false
+		default:
+		}
+		s.queueReady.Wait()
+	}
+	entry, _ := heap.Pop(&s.queue).(*taskEntry)
+	now := time.Now()
+	entry.startedAt = now
+	entry.task.QueueWait += now.Sub(entry.enqueuedAt)
+	if entry.task.StartedAt.IsZero() {
+		entry.task.StartedAt = now
+	}
+	entry.task.Status = constants.StatusRunning
+	entry.task.Attempts++
+	s.save(entry.task)
+	s.events.publish(EventStarted, entry.task)
+	return entry, true
+}
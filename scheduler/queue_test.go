@@ -0,0 +1,190 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+func TestQueue_FIFO(t *testing.T) {
+	s := NewScheduler(1)
+	defer s.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+		close(started)
+		<-release
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
+	<-started
+
+	order := make(chan int, 5)
+	for i := range 5 {
+		_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+			order <- i
+			return &models.Result{Summary: "ok"}, nil
+		}, TaskOptions{})
+	}
+	close(release)
+
+	for want := range 5 {
+		select {
+		case got := <-order:
+			if got != want {
+				t.Fatalf("expected task %d, got %d", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("queued task did not run")
+		}
+	}
+}
+
+func TestQueue_Full(t *testing.T) {
+	s := NewScheduler(1, WithMaxQueue(2))
+	defer s.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	block := func(context.Context) (*models.Result, error) {
+		<-release
+		return &models.Result{Summary: "ok"}, nil
+	}
+	_, _ = s.AddTask(func(ctx context.Context) (*models.Result, error) {
+		close(started)
+		return block(ctx)
+	}, TaskOptions{})
+	<-started
+
+	for range 2 {
+		if _, err := s.AddTask(block, TaskOptions{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := s.AddTask(block, TaskOptions{}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if _, err := s.AddTask(block, TaskOptions{RunAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("delayed tasks should not count toward the queue: %v", err)
+	}
+}
+
+func TestQueue_WorkerPool(t *testing.T) {
+	s := NewScheduler(2)
+	defer s.Stop()
+
+	release := make(chan struct{})
+	for range 4 {
+		_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+			<-release
+			return &models.Result{Summary: "ok"}, nil
+		}, TaskOptions{})
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	stats := s.GetStats()
+	if stats[constants.StatusRunning] != 2 || stats[constants.StatusPending] != 2 {
+		t.Errorf("expected 2 running and 2 pending tasks, got %v", stats)
+	}
+	close(release)
+}
+
+func TestStop(t *testing.T) {
+	s := NewScheduler(1)
+
+	id, _ := s.AddTask(func(ctx context.Context) (*models.Result, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, TaskOptions{Timeout: time.Second})
+	queued, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
+	time.Sleep(20 * time.Millisecond)
+
+	s.Stop()
+	time.Sleep(20 * time.Millisecond)
+
+	for _, taskID := range []string{id, queued} {
+		task, _ := s.GetTask(taskID)
+		if task.Status != constants.StatusCancelled {
+			t.Errorf("expected status %s, got %s", constants.StatusCancelled, task.Status)
+		}
+	}
+	if _, err := s.AddTask(func(context.Context) (*models.Result, error) { return &models.Result{Summary: "ok"}, nil }, TaskOptions{}); !errors.Is(err, ErrStopped) {
+		t.Errorf("expected ErrStopped, got %v", err)
+	}
+}
+
+func TestQueue_Priority(t *testing.T) {
+	s := NewScheduler(1, WithAging(0))
+	defer s.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+		close(started)
+		<-release
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
+	<-started
+
+	order := make(chan int, 3)
+	for _, priority := range []int{1, 9, 5} {
+		_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+			order <- priority
+			return &models.Result{Summary: "ok"}, nil
+		}, TaskOptions{Priority: priority})
+	}
+	depth := s.QueueDepth()
+	if depth[1] != 1 || depth[5] != 1 || depth[9] != 1 || depth[0] != 0 {
+		t.Errorf("unexpected queue depth: %v", depth)
+	}
+	close(release)
+
+	for _, want := range []int{9, 5, 1} {
+		select {
+		case got := <-order:
+			if got != want {
+				t.Fatalf("expected priority %d, got %d", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("queued task did not run")
+		}
+	}
+}
+
+func TestQueue_Aging(t *testing.T) {
+	s := NewScheduler(1, WithAging(10*time.Millisecond))
+	defer s.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+		close(started)
+		<-release
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
+	<-started
+
+	order := make(chan string, 2)
+	_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+		order <- "old"
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{Priority: constants.MinPriority})
+	// waiting 100ms ages the low priority task by 10 levels
+	time.Sleep(100 * time.Millisecond)
+	_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+		order <- "new"
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{Priority: constants.MaxPriority})
+	close(release)
+
+	if got := <-order; got != "old" {
+		t.Errorf("expected aged task to run first, got %s", got)
+	}
+}
@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/store"
+)
+
+// ErrInterrupted is the error of a task that was running when the process stopped
+var ErrInterrupted = errors.New("task interrupted by restart")
+
+// Builder rebuilds the function of a task loaded from the store, usually from its Spec
+type Builder func(task *models.Task) (TaskFunc, error)
+
+// RecoveryPolicy decides what happens to tasks that were running when the process stopped
+type RecoveryPolicy string
+
+const (
+	// RecoverFail marks interrupted tasks as failed
+	RecoverFail RecoveryPolicy = "fail"
+	// RecoverRequeue queues interrupted tasks again
+	RecoverRequeue RecoveryPolicy = "requeue"
+)
+
+// Recover queues again the unfinished tasks found in the store and returns how many were queued
+//
+// Scheduled tasks wait for their run time, pending and retrying tasks are
+// queued at once and running tasks are handled by the policy. Tasks that
+// cannot be rebuilt are marked as failed. Recovered tasks use the default
+// retry policy and are not counted against the queue limit.
+// Call it once, before new tasks are submitted.
+func (s *Scheduler) Recover(build Builder, policy RecoveryPolicy) (int, error) {
+	unfinished, err := s.store.List(store.Filter{
+		Statuses: []constants.TaskStatus{
+			constants.StatusScheduled, constants.StatusPending, constants.StatusRunning, constants.StatusRetrying,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.taskLock.Lock()
+	defer s.taskLock.Unlock()
+
+	select {
+	case <-s.done:
+		return 0, ErrStopped
+	default:
+	}
+	queued := 0
+	for _, task := range unfinished {
+		if _, ok := s.entries[task.ID]; ok {
+			continue
+		}
+		if task.Status == constants.StatusRunning && policy != RecoverRequeue {
+			s.fail(task, ErrInterrupted)
+			continue
+		}
+		fn, err := build(task)
+		if err != nil {
+			s.fail(task, err)
+			continue
+		}
+		opts := TaskOptions{
+			Type:     task.Type,
+			Timeout:  task.Timeout,
+			Retry:    &s.retry,
+			Priority: task.Priority,
+			RunAt:    task.RunAt,
+			Tags:     task.Tags,
+			Spec:     task.Spec,
+		}
+		if opts.Timeout <= 0 {
+			opts.Timeout = constants.TaskTimeout
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		entry := &taskEntry{ctx: ctx, cancel: cancel, task: task, fn: fn, opts: opts, finished: make(chan struct{})}
+		if task.Status == constants.StatusScheduled && task.RunAt.After(time.Now()) {
+			entry.due = task.RunAt
+		}
+		s.admit(entry)
+		queued++
+	}
+	return queued, nil
+}
+
+// fail finishes a task that is not tracked by the scheduler, the caller must hold taskLock
+func (s *Scheduler) fail(task *models.Task, err error) {
+	task.Err = err
+	task.AttemptErrors = append(task.AttemptErrors, err.Error())
+	s.finish(task, constants.StatusFailed)
+	s.save(task)
+}
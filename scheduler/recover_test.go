@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/store"
+)
+
+func TestShutdown_KeepsUnfinished(t *testing.T) {
+	st := store.NewMemoryStore()
+	s := NewScheduler(1, WithStore(st))
+
+	running, _ := s.AddTask(func(ctx context.Context) (*models.Result, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, TaskOptions{Timeout: time.Second})
+	pending, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
+	time.Sleep(20 * time.Millisecond)
+
+	s.Shutdown()
+	time.Sleep(20 * time.Millisecond)
+
+	for id, want := range map[string]constants.TaskStatus{running: constants.StatusRunning, pending: constants.StatusPending} {
+		task, _ := st.Get(id)
+		if task.Status != want {
+			t.Errorf("expected status %s, got %s", want, task.Status)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	st := store.NewMemoryStore()
+	now := time.Now()
+	_ = st.Put(&models.Task{ID: "pending", Status: constants.StatusPending, Spec: []byte("ok")})
+	_ = st.Put(&models.Task{ID: "running", Status: constants.StatusRunning, Attempts: 1, Spec: []byte("ok")})
+	_ = st.Put(&models.Task{ID: "scheduled", Status: constants.StatusScheduled, RunAt: now.Add(time.Hour), Spec: []byte("ok")})
+	_ = st.Put(&models.Task{ID: "broken", Status: constants.StatusPending, Spec: []byte("bad")})
+	_ = st.Put(&models.Task{ID: "done", Status: constants.StatusDone})
+
+	build := func(task *models.Task) (TaskFunc, error) {
+		if string(task.Spec) != "ok" {
+			return nil, errors.New("unknown spec")
+		}
+		return func(context.Context) (*models.Result, error) { return &models.Result{Summary: "ok"}, nil }, nil
+	}
+
+	tests := []struct {
+		name    string
+		policy  RecoveryPolicy
+		queued  int
+		running constants.TaskStatus
+	}{
+		{"fail", RecoverFail, 2, constants.StatusFailed},
+		{"requeue", RecoverRequeue, 3, constants.StatusDone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := store.NewMemoryStore()
+			all, _ := st.List(store.Filter{})
+			for _, task := range all {
+				_ = local.Put(task)
+			}
+			s := NewScheduler(2, WithStore(local))
+			defer s.Stop()
+
+			queued, err := s.Recover(build, tt.policy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if queued != tt.queued {
+				t.Errorf("expected %d queued tasks, got %d", tt.queued, queued)
+			}
+			time.Sleep(50 * time.Millisecond)
+
+			want := map[string]constants.TaskStatus{
+				"pending":   constants.StatusDone,
+				"running":   tt.running,
+				"scheduled": constants.StatusScheduled,
+				"broken":    constants.StatusFailed,
+				"done":      constants.StatusDone,
+			}
+			for id, status := range want {
+				task, _ := s.GetTask(id)
+				if task.Status != status {
+					t.Errorf("expected %s to be %s, got %s", id, status, task.Status)
+				}
+			}
+			if task, _ := s.GetTask("running"); tt.policy == RecoverFail && !errors.Is(task.Err, ErrInterrupted) {
+				t.Errorf("expected ErrInterrupted, got %v", task.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/store"
+)
+
+// RetentionPolicy limits how many finished tasks are kept and for how long
+//
+// Unfinished tasks are never removed. A zero limit is not enforced.
+type RetentionPolicy struct {
+	// MaxAge removes finished tasks older than the given duration
+	MaxAge time.Duration
+	// MaxCount keeps at most the given number of the newest finished tasks
+	MaxCount int
+	// StatusLimits keeps at most the given number of the newest finished tasks per final status
+	StatusLimits map[constants.TaskStatus]int
+	// SweepInterval is how often the limits are enforced, constants.SweepInterval is used when zero
+	SweepInterval time.Duration
+}
+
+// enabled reports whether the policy sets any limit
+func (p *RetentionPolicy) enabled() bool {
+	return p.MaxAge > 0 || p.MaxCount > 0 || len(p.StatusLimits) > 0
+}
+
+// WithRetention sets the retention policy of finished tasks and starts the background sweeper
+func WithRetention(policy RetentionPolicy) Option {
+	return func(s *Scheduler) {
+		s.retention = policy
+	}
+}
+
+// Expired reports whether a task with the given ID was removed by the retention policy since the service started
+func (s *Scheduler) Expired(id string) bool {
+	s.taskLock.RLock()
+	defer s.taskLock.RUnlock()
+	_, ok := s.expired[id]
+	return ok
+}
+
+// sweeper enforces the retention policy until the scheduler stops
+func (s *Scheduler) sweeper() {
+	interval := s.retention.SweepInterval
+	if interval <= 0 {
+		interval = constants.SweepInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.sweep(now)
+		}
+	}
+}
+
+// sweep removes finished tasks beyond the retention limits and forgets old expired IDs
+//
+// The store is read and written without taskLock, which is only taken to
+// forget old IDs and to record the removed ones.
+func (s *Scheduler) sweep(now time.Time) {
+	s.taskLock.Lock()
+	for id, expiredAt := range s.expired {
+		if now.Sub(expiredAt) > constants.TombstoneTTL {
+			delete(s.expired, id)
+		}
+	}
+	s.taskLock.Unlock()
+
+	candidates := s.sweepCandidates(now)
+	if len(candidates) == 0 {
+		return
+	}
+	// a task cancelled while running is tracked until its worker returns and stores the attempt
+	s.taskLock.RLock()
+	settled := candidates[:0]
+	for _, id := range candidates {
+		if _, ok := s.entries[id]; !ok {
+			settled = append(settled, id)
+		}
+	}
+	s.taskLock.RUnlock()
+
+	removed := make([]string, 0, len(settled))
+	for _, id := range settled {
+		if s.store.Delete(id) == nil {
+			removed = append(removed, id)
+		}
+	}
+	s.taskLock.Lock()
+	defer s.taskLock.Unlock()
+	for _, id := range removed {
+		s.expired[id] = now
+	}
+}
+
+// sweepCandidates returns the IDs of the finished tasks beyond the retention limits
+//
+// Finished tasks are listed newest first, constants.SweepPageSize at a time,
+// so that the limits keep the most recent ones. A store error ends the listing
+// with the candidates found so far.
+func (s *Scheduler) sweepCandidates(now time.Time) []string {
+	filter := store.Filter{
+		Statuses:   []constants.TaskStatus{constants.StatusDone, constants.StatusFailed, constants.StatusCancelled},
+		SortBy:     store.SortFinished,
+		Descending: true,
+		Limit:      constants.SweepPageSize,
+	}
+	var candidates []string
+	kept := 0
+	perStatus := make(map[constants.TaskStatus]int)
+	for {
+		page, err := s.store.List(filter)
+		if err != nil {
+			return candidates
+		}
+		for _, task := range page {
+			perStatus[task.Status]++
+			limit, limited := s.retention.StatusLimits[task.Status]
+			switch {
+			case s.retention.MaxAge > 0 && now.Sub(task.FinishedAt) > s.retention.MaxAge,
+				limited && limit > 0 && perStatus[task.Status] > limit,
+				s.retention.MaxCount > 0 && kept >= s.retention.MaxCount:
+				candidates = append(candidates, task.ID)
+			default:
+				kept++
+			}
+		}
+		if len(page) < filter.Limit {
+			return candidates
+		}
+		cursor := filter.CursorOf(page[len(page)-1])
+		filter.After = &cursor
+	}
+}
@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/store"
+)
+
+// finishTasks runs n tasks that succeed or fail and waits for them
+func finishTasks(t *testing.T, s *Scheduler, n int, fail bool) []string {
+	t.Helper()
+	ids := make([]string, 0, n)
+	for range n {
+		id, err := s.AddTask(func(context.Context) (*models.Result, error) {
+			if fail {
+				return nil, fmt.Errorf("failed")
+			}
+			return &models.Result{Summary: "ok"}, nil
+		}, TaskOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, id)
+		time.Sleep(5 * time.Millisecond)
+	}
+	return ids
+}
+
+func TestSweep_MaxAge(t *testing.T) {
+	s := NewScheduler(1, WithRetention(RetentionPolicy{MaxAge: time.Minute}))
+	defer s.Stop()
+
+	ids := finishTasks(t, s, 2, false)
+	release := make(chan struct{})
+	defer close(release)
+	running, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		<-release
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{Timeout: time.Hour})
+
+	s.sweep(time.Now().Add(2 * time.Minute))
+
+	for _, id := range ids {
+		if _, ok := s.GetTask(id); ok {
+			t.Errorf("task %s should be removed", id)
+		}
+		if !s.Expired(id) {
+			t.Errorf("task %s should be expired", id)
+		}
+		if err := s.Cancel(id); !errors.Is(err, ErrTaskExpired) {
+			t.Errorf("expected ErrTaskExpired, got %v", err)
+		}
+	}
+	if _, ok := s.GetTask(running); !ok {
+		t.Error("unfinished task should be kept")
+	}
+}
+
+func TestSweep_Limits(t *testing.T) {
+	s := NewScheduler(1, WithRetention(RetentionPolicy{
+		MaxCount:     3,
+		StatusLimits: map[constants.TaskStatus]int{constants.StatusFailed: 1},
+	}))
+	defer s.Stop()
+
+	failed := finishTasks(t, s, 2, true)
+	done := finishTasks(t, s, 4, false)
+
+	s.sweep(time.Now())
+
+	if _, ok := s.GetTask(failed[0]); ok {
+		t.Error("oldest failed task should be removed by the status limit")
+	}
+	if _, ok := s.GetTask(done[0]); ok {
+		t.Error("oldest done task should be removed by the count limit")
+	}
+	for _, id := range done[1:] {
+		if _, ok := s.GetTask(id); !ok {
+			t.Errorf("task %s should be kept", id)
+		}
+	}
+	if _, ok := s.GetTask(failed[1]); ok {
+		t.Error("failed task beyond the count limit should be removed")
+	}
+}
+
+func TestSweep_Tombstones(t *testing.T) {
+	s := NewScheduler(1, WithRetention(RetentionPolicy{MaxAge: time.Minute}))
+	defer s.Stop()
+
+	ids := finishTasks(t, s, 1, false)
+	s.sweep(time.Now().Add(2 * time.Minute))
+	s.sweep(time.Now().Add(2*time.Minute + constants.TombstoneTTL + time.Second))
+
+	if s.Expired(ids[0]) {
+		t.Error("old tombstone should be forgotten")
+	}
+}
+
+func TestSweep_Pages(t *testing.T) {
+	st := store.NewMemoryStore()
+	s := NewScheduler(1, WithStore(st), WithRetention(RetentionPolicy{MaxCount: 5}))
+	defer s.Stop()
+
+	start := time.Now().Add(-time.Hour)
+	total := constants.SweepPageSize + 10
+	for i := range total {
+		finishedAt := start.Add(time.Duration(i) * time.Second)
+		if err := st.Put(&models.Task{
+			ID:         fmt.Sprintf("task-%04d", i),
+			Status:     constants.StatusDone,
+			CreatedAt:  finishedAt,
+			FinishedAt: finishedAt,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.sweep(time.Now())
+
+	counts, _ := st.CountByStatus()
+	if counts[constants.StatusDone] != 5 {
+		t.Errorf("expected 5 tasks kept, got %d", counts[constants.StatusDone])
+	}
+	if !s.Expired("task-0000") || s.Expired(fmt.Sprintf("task-%04d", total-1)) {
+		t.Error("expected the oldest tasks to be removed and the newest kept")
+	}
+}
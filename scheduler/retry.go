@@ -0,0 +1,35 @@
+package scheduler
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy describes how failed task attempts are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of runs including the first one
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+	// Multiplier grows the delay after every failed attempt
+	Multiplier float64
+	// MaxBackoff caps the delay, no cap is applied when zero
+	MaxBackoff time.Duration
+	// Jitter is the fraction of the delay randomized in both directions, from 0 to 1
+	Jitter float64
+}
+
+// Backoff returns the delay to wait after the given failed attempt
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := math.Max(p.Multiplier, 1)
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 {
+		backoff = math.Min(backoff, float64(p.MaxBackoff))
+	}
+	if jitter := math.Min(math.Max(p.Jitter, 0), 1); jitter > 0 {
+		// #nosec G404 -- jitter does not need a cryptographic source
+		backoff += backoff * jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(backoff)
+}
@@ -0,0 +1,266 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	// ErrScheduleNotFound is returned when no schedule has the given ID
+	ErrScheduleNotFound = errors.New("schedule not found")
+	// ErrInvalidSchedule is returned when a schedule spec cannot be run
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+// ScheduleSpec describes a task submitted again and again, either at a fixed interval or by a cron expression
+type ScheduleSpec struct {
+	// Interval is the time between two runs
+	Interval time.Duration
+	// Cron is a cron expression with an optional leading seconds field, used instead of Interval
+	Cron string
+	// Timezone is the IANA time zone Cron is evaluated in, local time when empty
+	Timezone string
+	// Jitter adds a random delay up to the given value to every run
+	Jitter time.Duration
+	// SkipIfRunning skips a run while the previous child task has not finished
+	SkipIfRunning bool
+	// Task is the function every child task runs
+	Task TaskFunc
+	// Options are applied to every child task
+	Options TaskOptions
+}
+
+// schedule is a running recurring task
+type schedule struct {
+	info    models.Schedule
+	spec    ScheduleSpec
+	trigger trigger
+	cancel  context.CancelFunc
+}
+
+// trigger computes the fire times of a schedule
+type trigger interface {
+	// next returns the first fire time after the given one, or zero time if there is none
+	next(after time.Time) time.Time
+}
+
+// intervalTrigger fires at a fixed interval
+type intervalTrigger time.Duration
+
+func (t intervalTrigger) next(after time.Time) time.Time {
+	return after.Add(time.Duration(t))
+}
+
+// cronTrigger fires at the times matched by a cron expression
+type cronTrigger struct {
+	schedule cron.Schedule
+}
+
+func (t cronTrigger) next(after time.Time) time.Time {
+	return t.schedule.Next(after)
+}
+
+// ParseCron parses a cron expression in the given time zone
+//
+// The expression has five fields or six with a leading seconds field, and the
+// @yearly, @monthly, @weekly, @daily, @hourly and @every macros are accepted.
+func ParseCron(expr, timezone string) (cron.Schedule, error) {
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return nil, err
+		}
+		expr = "CRON_TZ=" + timezone + " " + expr
+	}
+	parser := cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	return parser.Parse(expr)
+}
+
+// newTrigger validates the timing of a spec and builds its trigger
+func newTrigger(spec *ScheduleSpec) (trigger, error) {
+	switch {
+	case spec.Cron != "" && spec.Interval != 0:
+		return nil, errors.New("interval and cron are mutually exclusive")
+	case spec.Cron != "":
+		parsed, err := ParseCron(spec.Cron, spec.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		if parsed.Next(time.Now()).IsZero() {
+			return nil, errors.New("cron expression never fires")
+		}
+		return cronTrigger{schedule: parsed}, nil
+	case spec.Timezone != "":
+		return nil, errors.New("timezone requires a cron expression")
+	case spec.Interval > 0:
+		return intervalTrigger(spec.Interval), nil
+	default:
+		return nil, errors.New("interval or cron is required")
+	}
+}
+
+// AddSchedule starts a recurring task and returns its ID
+func (s *Scheduler) AddSchedule(spec ScheduleSpec) (string, error) {
+	if spec.Jitter < 0 || spec.Task == nil {
+		return "", ErrInvalidSchedule
+	}
+	trig, err := newTrigger(&spec)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sc := &schedule{
+		info: models.Schedule{
+			ID:            uuid.NewString(),
+			Type:          spec.Options.Type,
+			Interval:      spec.Interval,
+			Cron:          spec.Cron,
+			Timezone:      spec.Timezone,
+			Jitter:        spec.Jitter,
+			SkipIfRunning: spec.SkipIfRunning,
+			CreatedAt:     time.Now(),
+		},
+		spec:    spec,
+		trigger: trig,
+		cancel:  cancel,
+	}
+
+	// checked under the lock Stop takes to cancel the schedules, so every schedule added is also stopped
+	s.scheduleLock.Lock()
+	if s.schedulesStopped {
+		s.scheduleLock.Unlock()
+		cancel()
+		return "", ErrStopped
+	}
+	s.schedules[sc.info.ID] = sc
+	s.scheduleLock.Unlock()
+
+	go s.runSchedule(ctx, sc)
+	return sc.info.ID, nil
+}
+
+// StopSchedule stops a recurring task, child tasks already submitted keep running
+func (s *Scheduler) StopSchedule(id string) error {
+	s.scheduleLock.Lock()
+	sc, ok := s.schedules[id]
+	delete(s.schedules, id)
+	s.scheduleLock.Unlock()
+	if !ok {
+		return ErrScheduleNotFound
+	}
+	sc.cancel()
+	return nil
+}
+
+// GetSchedule returns a snapshot of the schedule with the given ID, if it exists
+func (s *Scheduler) GetSchedule(id string) (models.Schedule, bool) {
+	s.scheduleLock.Lock()
+	defer s.scheduleLock.Unlock()
+	sc, ok := s.schedules[id]
+	if !ok {
+		return models.Schedule{}, false
+	}
+	return sc.info, true
+}
+
+// NextRuns returns the next n fire times of a schedule, without jitter
+func (s *Scheduler) NextRuns(id string, n int) ([]time.Time, error) {
+	s.scheduleLock.Lock()
+	sc, ok := s.schedules[id]
+	var after time.Time
+	if ok {
+		after = sc.info.NextRun
+	}
+	s.scheduleLock.Unlock()
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+
+	runs := make([]time.Time, 0, n)
+	if after.IsZero() || after.Before(time.Now()) {
+		after = sc.trigger.next(time.Now())
+	}
+	for ; len(runs) < n && !after.IsZero(); after = sc.trigger.next(after) {
+		runs = append(runs, after)
+	}
+	return runs, nil
+}
+
+// ListSchedules returns snapshots of all schedules ordered by creation time
+func (s *Scheduler) ListSchedules() []models.Schedule {
+	s.scheduleLock.Lock()
+	list := make([]models.Schedule, 0, len(s.schedules))
+	for _, sc := range s.schedules {
+		list = append(list, sc.info)
+	}
+	s.scheduleLock.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+func (s *Scheduler) runSchedule(ctx context.Context, sc *schedule) {
+	for {
+		now := time.Now()
+		next := sc.trigger.next(now)
+		if next.IsZero() {
+			// the cron expression has no more matches
+			return
+		}
+		delay := next.Sub(now)
+		if sc.spec.Jitter > 0 {
+			// #nosec G404 -- jitter does not need a cryptographic source
+			delay += rand.N(sc.spec.Jitter)
+		}
+		s.scheduleLock.Lock()
+		sc.info.NextRun = next
+		s.scheduleLock.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.fireSchedule(sc)
+	}
+}
+
+// fireSchedule submits the next child task unless the previous one is still running and must not overlap
+//
+// A run is also skipped when the run queue is full.
+func (s *Scheduler) fireSchedule(sc *schedule) {
+	s.scheduleLock.Lock()
+	lastTaskID := sc.info.LastTaskID
+	s.scheduleLock.Unlock()
+
+	if sc.spec.SkipIfRunning && lastTaskID != "" && s.isTaskActive(lastTaskID) {
+		s.scheduleLock.Lock()
+		sc.info.Skipped++
+		s.scheduleLock.Unlock()
+		return
+	}
+
+	taskID, err := s.submit(sc.spec.Task, sc.spec.Options, sc.info.ID)
+	if err != nil {
+		s.scheduleLock.Lock()
+		sc.info.Skipped++
+		s.scheduleLock.Unlock()
+		return
+	}
+
+	s.scheduleLock.Lock()
+	sc.info.LastTaskID = taskID
+	sc.info.Runs++
+	s.scheduleLock.Unlock()
+}
@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+func TestAddSchedule_Runs(t *testing.T) {
+	s := NewScheduler(2)
+
+	var calls atomic.Int32
+	id, err := s.AddSchedule(ScheduleSpec{
+		Interval: 20 * time.Millisecond,
+		Task: func(context.Context) (*models.Result, error) {
+			calls.Add(1)
+			return &models.Result{Summary: "ok"}, nil
+		},
+		Options: TaskOptions{Type: constants.TypePing},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(110 * time.Millisecond)
+	if err := s.StopSchedule(id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() < 3 {
+		t.Errorf("expected at least 3 runs, got %d", calls.Load())
+	}
+
+	stopped := calls.Load()
+	time.Sleep(50 * time.Millisecond)
+	if calls.Load() != stopped {
+		t.Error("stopped schedule should not submit tasks")
+	}
+}
+
+func TestAddSchedule_ChildTask(t *testing.T) {
+	s := NewScheduler(1)
+
+	id, _ := s.AddSchedule(ScheduleSpec{
+		Interval: 20 * time.Millisecond,
+		Task: func(context.Context) (*models.Result, error) {
+			return &models.Result{Summary: "ok"}, nil
+		},
+		Options: TaskOptions{Type: constants.TypePing},
+	})
+	defer func() { _ = s.StopSchedule(id) }()
+
+	time.Sleep(50 * time.Millisecond)
+	sc, ok := s.GetSchedule(id)
+	if !ok {
+		t.Fatal("schedule should exist")
+	}
+	task, ok := s.GetTask(sc.LastTaskID)
+	if !ok {
+		t.Fatal("child task should exist")
+	}
+	if task.ScheduleID != id || task.Type != constants.TypePing {
+		t.Errorf("unexpected child task: %+v", task)
+	}
+}
+
+func TestAddSchedule_SkipIfRunning(t *testing.T) {
+	s := NewScheduler(2)
+
+	var calls atomic.Int32
+	id, _ := s.AddSchedule(ScheduleSpec{
+		Interval:      20 * time.Millisecond,
+		SkipIfRunning: true,
+		Task: func(context.Context) (*models.Result, error) {
+			calls.Add(1)
+			time.Sleep(100 * time.Millisecond)
+			return &models.Result{Summary: "ok"}, nil
+		},
+	})
+	defer func() { _ = s.StopSchedule(id) }()
+
+	time.Sleep(90 * time.Millisecond)
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 run, got %d", calls.Load())
+	}
+	sc, _ := s.GetSchedule(id)
+	if sc.Skipped == 0 {
+		t.Error("expected skipped runs")
+	}
+}
+
+func TestAddSchedule_Invalid(t *testing.T) {
+	s := NewScheduler(1)
+
+	_, err := s.AddSchedule(ScheduleSpec{Task: func(context.Context) (*models.Result, error) { return nil, nil }})
+	if !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("expected ErrInvalidSchedule, got %v", err)
+	}
+	if err := s.StopSchedule("nonexistent"); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("expected ErrScheduleNotFound, got %v", err)
+	}
+}
+
+func TestAddSchedule_DuringStop(t *testing.T) {
+	task := func(context.Context) (*models.Result, error) { return nil, nil }
+	for range 50 {
+		s := NewScheduler(1)
+		added := make(chan error)
+		go func() {
+			_, err := s.AddSchedule(ScheduleSpec{Interval: time.Hour, Task: task})
+			added <- err
+		}()
+		s.Stop()
+		err := <-added
+		if err != nil && !errors.Is(err, ErrStopped) {
+			t.Fatalf("expected ErrStopped, got %v", err)
+		}
+		if len(s.ListSchedules()) != 0 {
+			t.Fatal("expected Stop to stop every schedule added meanwhile")
+		}
+		if _, err := s.AddSchedule(ScheduleSpec{Interval: time.Hour, Task: task}); !errors.Is(err, ErrStopped) {
+			t.Fatalf("expected ErrStopped after Stop, got %v", err)
+		}
+	}
+}
@@ -2,99 +2,518 @@
 package scheduler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"sync"
+	"time"
 
 	"github.com/artnikel/taskscheduler/constants"
 	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/store"
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrTaskNotFound is returned when no task has the given ID
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrTaskFinished is returned when a task has already reached a final status
+	ErrTaskFinished = errors.New("task already finished")
+	// ErrTaskExpired is returned when a task was removed by the retention policy
+	ErrTaskExpired = errors.New("task expired")
+	// ErrQueueFull is returned when the run queue has reached its maximum length
+	ErrQueueFull = errors.New("task queue is full")
+	// ErrStopped is returned when a task is submitted after Stop
+	ErrStopped = errors.New("scheduler stopped")
+)
+
 // TaskFunc defines the function signature for a scheduled task
-type TaskFunc func() (string, error)
+type TaskFunc func(ctx context.Context) (*models.Result, error)
+
+// TaskOptions holds per-task settings given at submission
+type TaskOptions struct {
+	// Type describes what the task does
+	Type constants.TaskType
+	// Timeout limits a single run of the task, constants.TaskTimeout is used when zero
+	Timeout time.Duration
+	// Retry overrides the scheduler default retry policy when set
+	Retry *RetryPolicy
+	// Priority orders the run queue from constants.MinPriority to constants.MaxPriority, higher runs first
+	Priority int
+	// RunAt delays the task until the given time, it is queued at once when zero or in the past
+	RunAt time.Time
+	// Tags are labels stored with the task to filter it later
+	Tags []string
+	// Spec is the encoded task description stored with the task, see Recover
+	Spec json.RawMessage
+	// CallbackURL receives the outcome of the task, see the webhook package
+	CallbackURL string
+}
 
 // Scheduler handles task management and concurrent execution
+//
+// A fixed pool of maxConcurrent workers pulls tasks from the run queue by
+// priority, and a queued task gains one priority level every aging interval
+// so that low priority tasks are not starved.
+// Delayed tasks and tasks waiting for a retry sit in a timer heap until due.
+// Task records live in a TaskStore, in memory unless WithStore is given,
+// and finished tasks are kept there until the retention policy removes them.
 type Scheduler struct {
 	maxConcurrent int
-	tasks         map[string]*models.Task
+	maxQueue      int
+	aging         time.Duration
+	retry         RetryPolicy
+	retention     RetentionPolicy
+	store         store.TaskStore
+	expired       map[string]time.Time
+	entries       map[string]*taskEntry
 	taskLock      sync.RWMutex
-	sem           chan struct{}
+	queue         runQueue
+	queueReady    *sync.Cond
+	seq           uint64
+	delayed       delayQueue
+	wake          chan struct{}
+	done          chan struct{}
+	stopOnce      sync.Once
+	events        *EventBus
+	// durations sums up the finished tasks, see Durations
+	durations durationTotals
+	// keepUnfinished leaves the records of unfinished tasks untouched after Shutdown
+	keepUnfinished bool
+	schedules      map[string]*schedule
+	// schedulesStopped is set by Stop so that no schedule is added after the running ones were cancelled
+	schedulesStopped bool
+	scheduleLock     sync.Mutex
+}
+
+// taskEntry holds the runtime state of an unfinished task
+type taskEntry struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	// task is the working copy of the record, written to the store on every change
+	task *models.Task
+	fn   TaskFunc
+	opts TaskOptions
+	// due is the time the entry leaves the timer heap
+	due time.Time
+	// rank orders the run queue, see enqueue
+	rank int64
+	// seq is the order the entry entered the run queue
+	seq uint64
+	// enqueuedAt is the time the entry last entered the run queue
+	enqueuedAt time.Time
+	// startedAt is the time the current attempt started
+	startedAt time.Time
+	// index is the position of the entry in the heap it currently sits in
+	index int
+	// finished is closed when the task reaches a final status
+	finished chan struct{}
+}
+
+// Option configures optional Scheduler settings
+type Option func(*Scheduler)
+
+// WithRetryPolicy sets the retry policy used by tasks submitted without their own
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(s *Scheduler) {
+		s.retry = policy
+	}
+}
+
+// WithMaxQueue limits the number of tasks waiting in the run queue, zero means no limit
+func WithMaxQueue(n int) Option {
+	return func(s *Scheduler) {
+		s.maxQueue = n
+	}
 }
 
-// NewScheduler creates a new Scheduler with the given concurrency limit
-func NewScheduler(maxConcurrent int) *Scheduler {
-	return &Scheduler{
+// WithAging sets how long a queued task waits to gain one priority level, zero disables aging
+func WithAging(interval time.Duration) Option {
+	return func(s *Scheduler) {
+		s.aging = interval
+	}
+}
+
+// WithStore sets the store task records are kept in
+func WithStore(st store.TaskStore) Option {
+	return func(s *Scheduler) {
+		s.store = st
+	}
+}
+
+// NewScheduler creates a new Scheduler with the given concurrency limit and starts its workers
+func NewScheduler(maxConcurrent int, opts ...Option) *Scheduler {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	s := &Scheduler{
 		maxConcurrent: maxConcurrent,
-		tasks:         make(map[string]*models.Task),
-		sem:           make(chan struct{}, maxConcurrent),
+		aging:         constants.AgingInterval,
+		retry:         RetryPolicy{MaxAttempts: 1},
+		store:         store.NewMemoryStore(),
+		expired:       make(map[string]time.Time),
+		entries:       make(map[string]*taskEntry),
+		wake:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+		schedules:     make(map[string]*schedule),
+		events:        NewEventBus(constants.EventReplaySize),
 	}
+	s.queueReady = sync.NewCond(&s.taskLock)
+	for _, opt := range opts {
+		opt(s)
+	}
+	for range s.maxConcurrent {
+		go s.worker()
+	}
+	go s.dispatchDelayed()
+	if s.retention.enabled() {
+		go s.sweeper()
+	}
+	return s
+}
+
+// AddTask adds a new task to the scheduler and runs it asynchronously
+func (s *Scheduler) AddTask(fn TaskFunc, opts TaskOptions) (string, error) {
+	return s.submit(fn, opts, "")
 }
 
-func (s *Scheduler) runTask(taskID string, fn TaskFunc) {
-	s.sem <- struct{}{}
-	defer func() { <-s.sem }()
+// submit registers a task, optionally owned by a schedule, and queues it
+func (s *Scheduler) submit(fn TaskFunc, opts TaskOptions, scheduleID string) (string, error) {
+	entry := s.newEntry(fn, opts)
+	entry.task.ScheduleID = scheduleID
 
 	s.taskLock.Lock()
-	task, exists := s.tasks[taskID]
-	if !exists {
-		s.taskLock.Unlock()
-		return
+	defer s.taskLock.Unlock()
+
+	select {
+	case <-s.done:
+		entry.cancel()
+		return "", ErrStopped
+	default:
 	}
-	task.Status = constants.StatusRunning
-	s.taskLock.Unlock()
+	if entry.due.IsZero() && s.maxQueue > 0 && s.queue.Len() >= s.maxQueue {
+		entry.cancel()
+		return "", ErrQueueFull
+	}
+	if err := s.store.Put(entry.task); err != nil {
+		entry.cancel()
+		return "", err
+	}
+	s.admit(entry)
+	return entry.task.ID, nil
+}
 
-	result, err := fn()
+// newEntry applies the option defaults and builds the record and runtime state of a new task
+//
+// A task due in the future gets the scheduled status and a due time.
+func (s *Scheduler) newEntry(fn TaskFunc, opts TaskOptions) *taskEntry {
+	if opts.Timeout <= 0 {
+		opts.Timeout = constants.TaskTimeout
+	}
+	if opts.Retry == nil {
+		opts.Retry = &s.retry
+	}
+	opts.Priority = min(max(opts.Priority, constants.MinPriority), constants.MaxPriority)
+	now := time.Now()
+	task := &models.Task{
+		ID:          uuid.NewString(),
+		Type:        opts.Type,
+		Status:      constants.StatusPending,
+		Priority:    opts.Priority,
+		CreatedAt:   now,
+		Timeout:     opts.Timeout,
+		Tags:        append([]string(nil), opts.Tags...),
+		Spec:        opts.Spec,
+		CallbackURL: opts.CallbackURL,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := &taskEntry{ctx: ctx, cancel: cancel, task: task, fn: fn, opts: opts, finished: make(chan struct{})}
+	if opts.RunAt.After(now) {
+		task.Status = constants.StatusScheduled
+		task.RunAt = opts.RunAt
+		entry.due = opts.RunAt
+	}
+	return entry
+}
 
+// admit starts tracking a stored task and queues it, the caller must hold taskLock
+func (s *Scheduler) admit(entry *taskEntry) {
+	s.entries[entry.task.ID] = entry
+	s.events.publish(EventCreated, entry.task)
+	if entry.due.IsZero() {
+		s.enqueue(entry)
+	} else {
+		s.pushDelayed(entry)
+	}
+}
+
+// finishAttempt records the outcome of a run and either finishes the task or schedules its retry
+func (s *Scheduler) finishAttempt(entry *taskEntry, result *models.Result, err error) {
 	s.taskLock.Lock()
 	defer s.taskLock.Unlock()
 
-	if err != nil {
-		task.Status = constants.StatusFailed
-		task.Err = err
+	if s.keepUnfinished {
 		return
 	}
-	task.Status = constants.StatusDone
-	task.Result = result
+	task := entry.task
+	defer s.save(task)
+	task.RunDuration += time.Since(entry.startedAt)
+	switch {
+	case !isActive(task.Status):
+		// cancelled while running, the task keeps the finish time of the cancellation
+	case errors.Is(entry.ctx.Err(), context.Canceled):
+		task.Err = entry.ctx.Err()
+		s.finish(task, constants.StatusCancelled)
+	case err == nil:
+		task.Result = result
+		task.Err = nil
+		s.finish(task, constants.StatusDone)
+	default:
+		task.AttemptErrors = append(task.AttemptErrors, err.Error())
+		task.Result = result
+		task.Err = err
+		if task.Attempts < entry.opts.Retry.MaxAttempts {
+			task.Status = constants.StatusRetrying
+			entry.due = time.Now().Add(entry.opts.Retry.Backoff(task.Attempts))
+			s.events.publish(EventRetried, task)
+			s.pushDelayed(entry)
+			return
+		}
+		s.finish(task, constants.StatusFailed)
+	}
+	// the run duration is final once the worker has returned
+	s.durations.add(task)
+	s.release(entry)
 }
 
-// AddTask adds a new task to the scheduler and runs it asynchronously
-func (s *Scheduler) AddTask(fn TaskFunc) string {
-	taskID := uuid.NewString()
-	task := &models.Task{
-		ID:     taskID,
-		Status: constants.StatusPending,
+// save writes the working copy of an unfinished task to the store, the caller must hold taskLock
+//
+// The working copy stays authoritative while the task runs, so a failed
+// write is picked up by the next change of the same task.
+func (s *Scheduler) save(task *models.Task) {
+	_ = s.store.Put(task)
+}
+
+// finish moves a task to a final status, adds it to the duration totals and wakes its waiters, the caller must hold taskLock
+//
+// Set the result and error of the task before, they are part of the published
+// event. A running task is added to the totals by finishAttempt once its
+// worker returns, and a task that has already finished is left as it is.
+func (s *Scheduler) finish(task *models.Task, status constants.TaskStatus) {
+	if !isActive(task.Status) {
+		return
 	}
+	running := task.Status == constants.StatusRunning
+	task.Status = status
+	task.FinishedAt = time.Now()
+	if !running {
+		s.durations.add(task)
+	}
+	if entry, ok := s.entries[task.ID]; ok {
+		close(entry.finished)
+	}
+	s.events.publish(finishEvent(status), task)
+}
 
+// release drops the runtime state of a finished task, the caller must hold taskLock
+func (s *Scheduler) release(entry *taskEntry) {
+	delete(s.entries, entry.task.ID)
+	entry.cancel()
+}
+
+// Cancel stops a task that has not finished yet
+//
+// Scheduled, pending and retrying tasks are removed from their queue at once,
+// running tasks are interrupted through their context.
+func (s *Scheduler) Cancel(id string) error {
 	s.taskLock.Lock()
-	s.tasks[taskID] = task
-	s.taskLock.Unlock()
+	defer s.taskLock.Unlock()
+
+	entry, ok := s.entries[id]
+	if !ok {
+		if _, expired := s.expired[id]; expired {
+			return ErrTaskExpired
+		}
+		if _, err := s.store.Get(id); err != nil {
+			return ErrTaskNotFound
+		}
+		return ErrTaskFinished
+	}
+	task := entry.task
+	if !isActive(task.Status) {
+		return ErrTaskFinished
+	}
+	switch task.Status {
+	case constants.StatusScheduled, constants.StatusRetrying:
+		s.removeDelayed(entry)
+		s.release(entry)
+	case constants.StatusPending:
+		s.dequeue(entry)
+		task.QueueWait += time.Since(entry.enqueuedAt)
+		s.release(entry)
+	default:
+		// the worker releases the entry once the task function returns
+		entry.cancel()
+	}
+	task.Err = context.Canceled
+	s.finish(task, constants.StatusCancelled)
+	s.save(task)
+	return nil
+}
+
+// Stop cancels every unfinished task and schedule and shuts the workers down
+func (s *Scheduler) Stop() {
+	s.stop(false)
+}
 
-	go s.runTask(taskID, fn)
-	return taskID
+// Shutdown stops like Stop but leaves the records of unfinished tasks as they are
+//
+// With a durable store the tasks are then picked up by Recover after a restart.
+func (s *Scheduler) Shutdown() {
+	s.stop(true)
 }
 
-// GetTask returns the task with the given ID, if it exists
+func (s *Scheduler) stop(keepUnfinished bool) {
+	s.stopOnce.Do(func() {
+		s.scheduleLock.Lock()
+		s.schedulesStopped = true
+		for id, sc := range s.schedules {
+			sc.cancel()
+			delete(s.schedules, id)
+		}
+		s.scheduleLock.Unlock()
+
+		s.taskLock.Lock()
+		close(s.done)
+		s.keepUnfinished = keepUnfinished
+		for _, entry := range s.entries {
+			if !keepUnfinished {
+				entry.task.Err = context.Canceled
+				s.finish(entry.task, constants.StatusCancelled)
+				s.save(entry.task)
+			}
+			entry.cancel()
+		}
+		s.queue = nil
+		s.delayed = nil
+		s.queueReady.Broadcast()
+		s.events.Close()
+		s.taskLock.Unlock()
+	})
+}
+
+// GetTask returns a snapshot of the task with the given ID, if it exists
 func (s *Scheduler) GetTask(id string) (*models.Task, bool) {
+	task, err := s.store.Get(id)
+	if err != nil {
+		return nil, false
+	}
+	return task, true
+}
+
+// RecordDelivery appends a callback delivery attempt to the log of a task
+func (s *Scheduler) RecordDelivery(id string, delivery models.Delivery) error {
+	// the lock keeps the final save of the task from overwriting the log
+	s.taskLock.Lock()
+	defer s.taskLock.Unlock()
+	err := s.store.Update(id, func(task *models.Task) {
+		task.Deliveries = append(task.Deliveries, delivery)
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrTaskNotFound
+	}
+	return err
+}
+
+// Wait blocks until the task with the given ID reaches a final status or ctx is done, and returns its latest snapshot
+//
+// Waiting is driven by a per-task notification, the store is read only once at the end.
+func (s *Scheduler) Wait(ctx context.Context, id string) (*models.Task, error) {
+	s.taskLock.RLock()
+	entry, ok := s.entries[id]
+	s.taskLock.RUnlock()
+	if ok {
+		select {
+		case <-entry.finished:
+		case <-ctx.Done():
+		}
+	}
+
+	// the final status is saved before taskLock is released
+	s.taskLock.RLock()
+	task, err := s.store.Get(id)
+	_, expired := s.expired[id]
+	s.taskLock.RUnlock()
+	switch {
+	case err == nil:
+		return task, nil
+	case expired:
+		return nil, ErrTaskExpired
+	case errors.Is(err, store.ErrNotFound):
+		return nil, ErrTaskNotFound
+	default:
+		return nil, err
+	}
+}
+
+// List returns snapshots of the tasks matching the filter in the order it asks for
+func (s *Scheduler) List(filter store.Filter) ([]*models.Task, error) {
+	return s.store.List(filter)
+}
+
+// QueueDepth returns the number of tasks waiting in the run queue by their priority
+func (s *Scheduler) QueueDepth() map[int]int {
+	depth := make(map[int]int, constants.MaxPriority-constants.MinPriority+1)
+	for p := constants.MinPriority; p <= constants.MaxPriority; p++ {
+		depth[p] = 0
+	}
+
 	s.taskLock.RLock()
 	defer s.taskLock.RUnlock()
-	task, ok := s.tasks[id]
-	return task, ok
+
+	for _, entry := range s.queue {
+		depth[entry.opts.Priority]++
+	}
+	return depth
+}
+
+// isTaskActive reports whether the task with the given ID exists and has not finished yet
+func (s *Scheduler) isTaskActive(id string) bool {
+	s.taskLock.RLock()
+	defer s.taskLock.RUnlock()
+	entry, ok := s.entries[id]
+	return ok && isActive(entry.task.Status)
 }
 
 // GetStats returns the count of tasks by their status
 func (s *Scheduler) GetStats() map[constants.TaskStatus]int {
 	stats := map[constants.TaskStatus]int{
-		constants.StatusPending: 0,
-		constants.StatusRunning: 0,
-		constants.StatusDone:    0,
-		constants.StatusFailed:  0,
+		constants.StatusScheduled: 0,
+		constants.StatusPending:   0,
+		constants.StatusRunning:   0,
+		constants.StatusRetrying:  0,
+		constants.StatusDone:      0,
+		constants.StatusFailed:    0,
+		constants.StatusCancelled: 0,
 	}
 
-	s.taskLock.RLock()
-	defer s.taskLock.RUnlock()
-
-	for _, task := range s.tasks {
-		stats[task.Status]++
+	counts, err := s.store.CountByStatus()
+	if err != nil {
+		return stats
+	}
+	for status, n := range counts {
+		stats[status] = n
 	}
 
 	return stats
 }
+
+// isActive reports whether a task with the given status has not finished yet
+func isActive(status constants.TaskStatus) bool {
+	switch status {
+	case constants.StatusScheduled, constants.StatusPending, constants.StatusRunning, constants.StatusRetrying:
+		return true
+	default:
+		return false
+	}
+}
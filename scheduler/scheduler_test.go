@@ -1,20 +1,25 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/store"
 )
 
 func TestAddTask_Success(t *testing.T) {
 	s := NewScheduler(2)
 
-	id := s.AddTask(func() (string, error) {
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
 		time.Sleep(100 * time.Millisecond)
-		return "ok", nil
-	})
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
 
 	time.Sleep(200 * time.Millisecond)
 	task, ok := s.GetTask(id)
@@ -24,18 +29,18 @@ func TestAddTask_Success(t *testing.T) {
 	if task.Status != constants.StatusDone {
 		t.Errorf("expected status %s, got %s", constants.StatusDone, task.Status)
 	}
-	if task.Result != "ok" {
-		t.Errorf("expected result 'ok', got %s", task.Result)
+	if task.Result == nil || task.Result.Summary != "ok" {
+		t.Errorf("expected result 'ok', got %v", task.Result)
 	}
 }
 
 func TestAddTask_Failure(t *testing.T) {
 	s := NewScheduler(1)
 
-	id := s.AddTask(func() (string, error) {
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
 		time.Sleep(50 * time.Millisecond)
-		return "", fmt.Errorf("failed")
-	})
+		return nil, fmt.Errorf("failed")
+	}, TaskOptions{})
 
 	time.Sleep(100 * time.Millisecond)
 	task, ok := s.GetTask(id)
@@ -50,6 +55,26 @@ func TestAddTask_Failure(t *testing.T) {
 	}
 }
 
+func TestAddTask_FailureWithResult(t *testing.T) {
+	s := NewScheduler(1)
+
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "2 checks failed"}, fmt.Errorf("failed")
+	}, TaskOptions{})
+
+	time.Sleep(50 * time.Millisecond)
+	task, ok := s.GetTask(id)
+	if !ok {
+		t.Fatal("task should exist")
+	}
+	if task.Status != constants.StatusFailed {
+		t.Errorf("expected status %s, got %s", constants.StatusFailed, task.Status)
+	}
+	if task.Result == nil || task.Result.Summary != "2 checks failed" {
+		t.Errorf("expected the result of the failed run, got %+v", task.Result)
+	}
+}
+
 func TestGetTask_NotFound(t *testing.T) {
 	s := NewScheduler(1)
 	_, ok := s.GetTask("nonexistent")
@@ -57,3 +82,264 @@ func TestGetTask_NotFound(t *testing.T) {
 		t.Error("expected false, got true")
 	}
 }
+
+func TestAddTask_Timeout(t *testing.T) {
+	s := NewScheduler(1)
+
+	id, _ := s.AddTask(func(ctx context.Context) (*models.Result, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, TaskOptions{Timeout: 50 * time.Millisecond})
+
+	time.Sleep(100 * time.Millisecond)
+	task, ok := s.GetTask(id)
+	if !ok {
+		t.Fatal("task should exist")
+	}
+	if task.Status != constants.StatusFailed {
+		t.Errorf("expected status %s, got %s", constants.StatusFailed, task.Status)
+	}
+	if !errors.Is(task.Err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", task.Err)
+	}
+}
+
+func TestCancel_Pending(t *testing.T) {
+	s := NewScheduler(1)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+		close(started)
+		<-release
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
+	<-started
+	ran := make(chan struct{}, 1)
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		ran <- struct{}{}
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
+
+	time.Sleep(20 * time.Millisecond)
+	if err := s.Cancel(id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	task, _ := s.GetTask(id)
+	if task.Status != constants.StatusCancelled {
+		t.Errorf("expected status %s, got %s", constants.StatusCancelled, task.Status)
+	}
+	select {
+	case <-ran:
+		t.Error("cancelled pending task should not run")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestCancel_Running(t *testing.T) {
+	s := NewScheduler(1)
+
+	id, _ := s.AddTask(func(ctx context.Context) (*models.Result, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, TaskOptions{Timeout: time.Second})
+
+	time.Sleep(20 * time.Millisecond)
+	if err := s.Cancel(id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	task, _ := s.GetTask(id)
+	if task.Status != constants.StatusCancelled {
+		t.Errorf("expected status %s, got %s", constants.StatusCancelled, task.Status)
+	}
+	if !errors.Is(task.Err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", task.Err)
+	}
+}
+
+func TestCancel_RunningDurations(t *testing.T) {
+	s := NewScheduler(1)
+	defer s.Stop()
+
+	id, _ := s.AddTask(func(ctx context.Context) (*models.Result, error) {
+		<-ctx.Done()
+		time.Sleep(30 * time.Millisecond)
+		return nil, ctx.Err()
+	}, TaskOptions{Timeout: time.Second})
+
+	time.Sleep(20 * time.Millisecond)
+	if err := s.Cancel(id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cancelled, _ := s.GetTask(id)
+	if d := s.Durations(); d.Finished != 0 {
+		t.Errorf("expected the task to be counted once its worker returns, got %+v", d)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	task, _ := s.GetTask(id)
+	if !task.FinishedAt.Equal(cancelled.FinishedAt) {
+		t.Errorf("expected finish time %v of the cancellation, got %v", cancelled.FinishedAt, task.FinishedAt)
+	}
+	if task.RunDuration < 50*time.Millisecond {
+		t.Errorf("expected run duration of at least 50ms, got %v", task.RunDuration)
+	}
+	if d := s.Durations(); d.Finished != 1 || d.MaxRunDuration != task.RunDuration {
+		t.Errorf("expected the task counted once with its run duration, got %+v", d)
+	}
+}
+
+func TestCancel_Finished(t *testing.T) {
+	s := NewScheduler(1)
+
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
+
+	time.Sleep(20 * time.Millisecond)
+	if err := s.Cancel(id); !errors.Is(err, ErrTaskFinished) {
+		t.Errorf("expected ErrTaskFinished, got %v", err)
+	}
+	if err := s.Cancel("nonexistent"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestAddTask_Retry(t *testing.T) {
+	s := NewScheduler(1, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond}))
+
+	var calls atomic.Int32
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		if calls.Add(1) < 3 {
+			return nil, fmt.Errorf("transient")
+		}
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
+
+	time.Sleep(100 * time.Millisecond)
+	task, _ := s.GetTask(id)
+	if task.Status != constants.StatusDone {
+		t.Errorf("expected status %s, got %s", constants.StatusDone, task.Status)
+	}
+	if task.Attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", task.Attempts)
+	}
+	if len(task.AttemptErrors) != 2 {
+		t.Errorf("expected 2 attempt errors, got %v", task.AttemptErrors)
+	}
+}
+
+func TestAddTask_RetryExhausted(t *testing.T) {
+	s := NewScheduler(1)
+
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		return nil, fmt.Errorf("down")
+	}, TaskOptions{Retry: &RetryPolicy{MaxAttempts: 2, InitialBackoff: 100 * time.Millisecond}})
+
+	time.Sleep(50 * time.Millisecond)
+	task, _ := s.GetTask(id)
+	if task.Status != constants.StatusRetrying {
+		t.Errorf("expected status %s, got %s", constants.StatusRetrying, task.Status)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	task, _ = s.GetTask(id)
+	if task.Status != constants.StatusFailed {
+		t.Errorf("expected status %s, got %s", constants.StatusFailed, task.Status)
+	}
+	if task.Attempts != 2 || len(task.AttemptErrors) != 2 {
+		t.Errorf("expected 2 failed attempts, got %d with errors %v", task.Attempts, task.AttemptErrors)
+	}
+}
+
+func TestWithStore(t *testing.T) {
+	st := store.NewMemoryStore()
+	s := NewScheduler(1, WithStore(st))
+	defer s.Stop()
+
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{Type: constants.TypePing, Tags: []string{"nightly"}})
+	time.Sleep(50 * time.Millisecond)
+
+	task, err := st.Get(id)
+	if err != nil {
+		t.Fatalf("expected task in the given store: %v", err)
+	}
+	if task.Status != constants.StatusDone {
+		t.Errorf("expected status done, got %s", task.Status)
+	}
+	list, _ := s.List(store.Filter{Tag: "nightly"})
+	if len(list) != 1 || list[0].ID != id {
+		t.Errorf("expected tagged task in list, got %v", list)
+	}
+	if s.GetStats()[constants.StatusDone] != 1 {
+		t.Errorf("expected 1 done task in stats")
+	}
+}
+
+func TestTaskDurations(t *testing.T) {
+	s := NewScheduler(1)
+	defer s.Stop()
+
+	release := make(chan struct{})
+	_, _ = s.AddTask(func(context.Context) (*models.Result, error) {
+		<-release
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
+	id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+		time.Sleep(30 * time.Millisecond)
+		return &models.Result{Summary: "ok"}, nil
+	}, TaskOptions{})
+	time.Sleep(40 * time.Millisecond)
+	close(release)
+	time.Sleep(60 * time.Millisecond)
+
+	task, _ := s.GetTask(id)
+	if task.StartedAt.Before(task.CreatedAt) || task.FinishedAt.Before(task.StartedAt) {
+		t.Errorf("expected created <= started <= finished, got %v %v %v", task.CreatedAt, task.StartedAt, task.FinishedAt)
+	}
+	if task.QueueWait < 40*time.Millisecond {
+		t.Errorf("expected queue wait of at least 40ms, got %v", task.QueueWait)
+	}
+	if task.RunDuration < 30*time.Millisecond {
+		t.Errorf("expected run duration of at least 30ms, got %v", task.RunDuration)
+	}
+	d := s.Durations()
+	if d.Finished != 2 || d.MaxQueueWait != task.QueueWait || d.MaxRunDuration < 40*time.Millisecond {
+		t.Errorf("unexpected duration stats: %+v", d)
+	}
+}
+
+func TestWait(t *testing.T) {
+	s := NewScheduler(1)
+	defer s.Stop()
+
+	id, _ := s.AddTask(func(ctx context.Context) (*models.Result, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, TaskOptions{Timeout: time.Second})
+	time.Sleep(20 * time.Millisecond)
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		_ = s.Cancel(id)
+	}()
+	task, err := s.Wait(context.Background(), id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.Status != constants.StatusCancelled {
+		t.Errorf("expected status %s, got %s", constants.StatusCancelled, task.Status)
+	}
+
+	// a finished task returns at once
+	if task, _ := s.Wait(context.Background(), id); task.Status != constants.StatusCancelled {
+		t.Errorf("expected status %s, got %s", constants.StatusCancelled, task.Status)
+	}
+	if _, err := s.Wait(context.Background(), "missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+}
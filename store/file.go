@@ -0,0 +1,321 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+const (
+	// walFile is the append-only log of changes since the last snapshot
+	walFile = "tasks.wal"
+	// snapshotFile holds every task at the time of the last snapshot
+	snapshotFile = "tasks.snapshot"
+
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// walRecord is one line of the write-ahead log
+type walRecord struct {
+	Op   string          `json:"op"`
+	ID   string          `json:"id,omitempty"`
+	Task json.RawMessage `json:"task,omitempty"`
+}
+
+// FileStore keeps tasks in memory and makes every change durable in a data directory
+//
+// Each change is appended to a write-ahead log and synced before it is
+// applied. A snapshot of all tasks is written at a fixed interval and on
+// Close, after which the log starts over. Opening the store loads the
+// snapshot and replays the log, a torn record at the end of the log is dropped.
+type FileStore struct {
+	mem      *MemoryStore
+	dir      string
+	wal      *os.File
+	lock     sync.Mutex
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// OpenFileStore opens or creates a file store in dir and snapshots it every interval
+//
+// constants.SnapshotInterval is used when interval is zero.
+func OpenFileStore(dir string, interval time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(dir, constants.DirPerm); err != nil {
+		return nil, err
+	}
+	f := &FileStore{mem: NewMemoryStore(), dir: dir, done: make(chan struct{})}
+	if err := f.loadSnapshot(); err != nil {
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	if err := f.replay(); err != nil {
+		return nil, fmt.Errorf("failed to replay log: %w", err)
+	}
+	if interval <= 0 {
+		interval = constants.SnapshotInterval
+	}
+	go f.snapshotter(interval)
+	return f, nil
+}
+
+// loadSnapshot fills the memory store from the last snapshot, if there is one
+func (f *FileStore) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(f.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var records []json.RawMessage
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+	for _, raw := range records {
+		task, err := decodeTask(raw)
+		if err != nil {
+			return err
+		}
+		_ = f.mem.Put(task)
+	}
+	return nil
+}
+
+// replay applies the log on top of the snapshot and opens it for appending
+//
+// Only the last record may be torn by a crash, it is cut off. A record that
+// does not decode before the end of the log fails the replay.
+func (f *FileStore) replay() error {
+	wal, err := os.OpenFile(filepath.Join(f.dir, walFile), os.O_RDWR|os.O_CREATE, constants.FilePerm)
+	if err != nil {
+		return err
+	}
+	var valid int64
+	reader := bufio.NewReader(wal)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// a line without its newline was torn by a crash
+			break
+		}
+		if err != nil {
+			_ = wal.Close()
+			return err
+		}
+		if err := f.apply(bytes.TrimSpace(line)); err != nil {
+			if _, peekErr := reader.Peek(1); errors.Is(peekErr, io.EOF) {
+				// the last line was torn by a crash
+				break
+			}
+			_ = wal.Close()
+			return fmt.Errorf("corrupt record at offset %d: %w", valid, err)
+		}
+		valid += int64(len(line))
+	}
+	if err := wal.Truncate(valid); err != nil {
+		_ = wal.Close()
+		return err
+	}
+	if _, err := wal.Seek(valid, io.SeekStart); err != nil {
+		_ = wal.Close()
+		return err
+	}
+	f.wal = wal
+	return nil
+}
+
+// apply replays one log record into the memory store
+func (f *FileStore) apply(line []byte) error {
+	var rec walRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return err
+	}
+	switch rec.Op {
+	case opPut:
+		task, err := decodeTask(rec.Task)
+		if err != nil {
+			return err
+		}
+		return f.mem.Put(task)
+	case opDelete:
+		return f.mem.Delete(rec.ID)
+	default:
+		return fmt.Errorf("unknown log operation %q", rec.Op)
+	}
+}
+
+// appendRecord writes a record to the log and syncs it, the caller must hold lock
+func (f *FileStore) appendRecord(rec walRecord) error {
+	if f.wal == nil {
+		return errors.New("file store closed")
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := f.wal.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.wal.Sync()
+}
+
+// Put inserts a task or replaces the task with the same ID
+func (f *FileStore) Put(task *models.Task) error {
+	data, err := encodeTask(task)
+	if err != nil {
+		return err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if err := f.appendRecord(walRecord{Op: opPut, Task: data}); err != nil {
+		return err
+	}
+	return f.mem.Put(task)
+}
+
+// Get returns the task with the given ID or ErrNotFound
+func (f *FileStore) Get(id string) (*models.Task, error) {
+	return f.mem.Get(id)
+}
+
+// Update applies fn to the stored task with the given ID or returns ErrNotFound
+func (f *FileStore) Update(id string, fn func(task *models.Task)) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	task, err := f.mem.Get(id)
+	if err != nil {
+		return err
+	}
+	fn(task)
+	task.ID = id
+	data, err := encodeTask(task)
+	if err != nil {
+		return err
+	}
+	if err := f.appendRecord(walRecord{Op: opPut, Task: data}); err != nil {
+		return err
+	}
+	return f.mem.Put(task)
+}
+
+// List returns the tasks matching the filter in the order it asks for
+func (f *FileStore) List(filter Filter) ([]*models.Task, error) {
+	return f.mem.List(filter)
+}
+
+// Delete removes the task with the given ID
+func (f *FileStore) Delete(id string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if _, err := f.mem.Get(id); errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err := f.appendRecord(walRecord{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+	return f.mem.Delete(id)
+}
+
+// CountByStatus returns the number of tasks in every status
+func (f *FileStore) CountByStatus() (map[constants.TaskStatus]int, error) {
+	return f.mem.CountByStatus()
+}
+
+// Snapshot writes every task to the snapshot file and starts a new log
+func (f *FileStore) Snapshot() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.snapshot()
+}
+
+// snapshot replaces the snapshot file atomically, the caller must hold lock
+func (f *FileStore) snapshot() error {
+	if f.wal == nil {
+		return errors.New("file store closed")
+	}
+	all, err := f.mem.List(Filter{})
+	if err != nil {
+		return err
+	}
+	records := make([]json.RawMessage, 0, len(all))
+	for _, task := range all {
+		data, err := encodeTask(task)
+		if err != nil {
+			return err
+		}
+		records = append(records, data)
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(f.dir, snapshotFile+".tmp")
+	// #nosec G304 -- the data directory comes from the trusted config
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, constants.FilePerm)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(data); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(f.dir, snapshotFile)); err != nil {
+		return err
+	}
+	// replaying records already in the snapshot is harmless, so a crash here loses nothing
+	if err := f.wal.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.wal.Seek(0, io.SeekStart)
+	return err
+}
+
+// snapshotter writes a snapshot at every interval until the store is closed
+func (f *FileStore) snapshotter(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-ticker.C:
+			_ = f.Snapshot()
+		}
+	}
+}
+
+// Close writes a final snapshot and closes the log
+func (f *FileStore) Close() error {
+	var err error
+	f.stopOnce.Do(func() {
+		close(f.done)
+		f.lock.Lock()
+		defer f.lock.Unlock()
+		err = f.snapshot()
+		if closeErr := f.wal.Close(); err == nil {
+			err = closeErr
+		}
+		f.wal = nil
+	})
+	return err
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+func TestFileStore_ReplayLog(t *testing.T) {
+	dir := t.TempDir()
+	f, err := OpenFileStore(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = f.Put(&models.Task{ID: "a", Status: constants.StatusPending, Spec: []byte(`{"type":"ping"}`)})
+	_ = f.Put(&models.Task{ID: "b", Status: constants.StatusPending})
+	_ = f.Update("a", func(task *models.Task) {
+		task.Status = constants.StatusFailed
+		task.Err = errors.New("boom")
+	})
+	_ = f.Delete("b")
+
+	// reopen without Close, as after a crash
+	reopened, err := OpenFileStore(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reopened.Close()
+
+	task, err := reopened.Get("a")
+	if err != nil {
+		t.Fatalf("expected task a after replay: %v", err)
+	}
+	if task.Status != constants.StatusFailed || task.Err == nil || task.Err.Error() != "boom" {
+		t.Errorf("unexpected replayed task: %+v", task)
+	}
+	if string(task.Spec) != `{"type":"ping"}` {
+		t.Errorf("expected spec to survive, got %s", task.Spec)
+	}
+	if _, err := reopened.Get("b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected deleted task to stay deleted, got %v", err)
+	}
+}
+
+func TestFileStore_SnapshotAndTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	f, err := OpenFileStore(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = f.Put(&models.Task{ID: "a", Status: constants.StatusDone})
+	if err := f.Snapshot(); err != nil {
+		t.Fatalf("unexpected snapshot error: %v", err)
+	}
+	_ = f.Put(&models.Task{ID: "b", Status: constants.StatusPending})
+
+	// simulate a write torn by a crash
+	wal, _ := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND, constants.FilePerm)
+	_, _ = wal.WriteString(`{"op":"put","task":{"ID":"c"`)
+	_ = wal.Close()
+
+	reopened, err := OpenFileStore(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	counts, _ := reopened.CountByStatus()
+	if counts[constants.StatusDone] != 1 || counts[constants.StatusPending] != 1 {
+		t.Errorf("unexpected counts after replay: %v", counts)
+	}
+	if _, err := reopened.Get("c"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected torn record to be dropped, got %v", err)
+	}
+	// the log keeps working after the torn record is cut off
+	_ = reopened.Put(&models.Task{ID: "d", Status: constants.StatusPending})
+	if err := reopened.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+
+	last, err := OpenFileStore(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer last.Close()
+	list, _ := last.List(Filter{})
+	if len(list) != 3 {
+		t.Errorf("expected 3 tasks, got %d", len(list))
+	}
+}
+
+func TestFileStore_CorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	f, err := OpenFileStore(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = f.Put(&models.Task{ID: "a", Status: constants.StatusDone})
+	_ = f.Put(&models.Task{ID: "b", Status: constants.StatusDone})
+
+	// a damaged record followed by valid ones is not a torn write
+	path := filepath.Join(dir, walFile)
+	data, _ := os.ReadFile(path)
+	first := bytes.IndexByte(data, '\n') + 1
+	damaged := append(append(append([]byte(nil), data[:first]...), "{\"op\":\"put\",\"task\":{\"ID\":\"c\"\n"...), data[first:]...)
+	if err := os.WriteFile(path, damaged, constants.FilePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenFileStore(dir, time.Hour); err == nil {
+		t.Fatal("expected a corrupt record in the middle of the log to fail the replay")
+	}
+	if kept, _ := os.ReadFile(path); !bytes.Equal(kept, damaged) {
+		t.Error("expected the log to be left as it was")
+	}
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+// MemoryStore keeps tasks in a map and loses them when the process exits
+type MemoryStore struct {
+	tasks  map[string]*models.Task
+	counts map[constants.TaskStatus]int
+	lock   sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tasks:  make(map[string]*models.Task),
+		counts: make(map[constants.TaskStatus]int),
+	}
+}
+
+// Put inserts a task or replaces the task with the same ID
+func (m *MemoryStore) Put(task *models.Task) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if old, ok := m.tasks[task.ID]; ok {
+		m.counts[old.Status]--
+	}
+	m.tasks[task.ID] = task.Clone()
+	m.counts[task.Status]++
+	return nil
+}
+
+// Get returns the task with the given ID or ErrNotFound
+func (m *MemoryStore) Get(id string) (*models.Task, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	task, ok := m.tasks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return task.Clone(), nil
+}
+
+// Update applies fn to the stored task with the given ID or returns ErrNotFound
+func (m *MemoryStore) Update(id string, fn func(task *models.Task)) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	task, ok := m.tasks[id]
+	if !ok {
+		return ErrNotFound
+	}
+	m.counts[task.Status]--
+	fn(task)
+	task.ID = id
+	m.counts[task.Status]++
+	return nil
+}
+
+// List returns the tasks matching the filter in the order it asks for
+func (m *MemoryStore) List(filter Filter) ([]*models.Task, error) {
+	m.lock.RLock()
+	list := make([]*models.Task, 0)
+	for _, task := range m.tasks {
+		if filter.Match(task) {
+			list = append(list, task.Clone())
+		}
+	}
+	m.lock.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return filter.Less(list[i], list[j])
+	})
+	if filter.Limit > 0 && len(list) > filter.Limit {
+		list = list[:filter.Limit]
+	}
+	return list, nil
+}
+
+// Delete removes the task with the given ID
+func (m *MemoryStore) Delete(id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if task, ok := m.tasks[id]; ok {
+		m.counts[task.Status]--
+		delete(m.tasks, id)
+	}
+	return nil
+}
+
+// CountByStatus returns the number of tasks in every status from the kept counters
+func (m *MemoryStore) CountByStatus() (map[constants.TaskStatus]int, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	counts := make(map[constants.TaskStatus]int, len(m.counts))
+	for status, n := range m.counts {
+		if n > 0 {
+			counts[status] = n
+		}
+	}
+	return counts, nil
+}
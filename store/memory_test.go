@@ -0,0 +1,99 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+func TestMemoryStore_PutGet(t *testing.T) {
+	m := NewMemoryStore()
+	task := &models.Task{ID: "a", Status: constants.StatusPending, Tags: []string{"x"}}
+	if err := m.Put(task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	task.Tags[0] = "changed"
+
+	got, err := m.Get("a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Tags[0] != "x" {
+		t.Errorf("expected stored copy to keep tag x, got %q", got.Tags[0])
+	}
+	if _, err := m.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryStore_UpdateCounts(t *testing.T) {
+	m := NewMemoryStore()
+	_ = m.Put(&models.Task{ID: "a", Status: constants.StatusPending})
+	_ = m.Put(&models.Task{ID: "b", Status: constants.StatusPending})
+
+	err := m.Update("a", func(task *models.Task) { task.Status = constants.StatusDone })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = m.Put(&models.Task{ID: "b", Status: constants.StatusFailed})
+	_ = m.Delete("b")
+
+	counts, _ := m.CountByStatus()
+	if counts[constants.StatusDone] != 1 || counts[constants.StatusPending] != 0 || counts[constants.StatusFailed] != 0 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+	if err := m.Update("missing", func(*models.Task) {}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryStore_List(t *testing.T) {
+	testStoreList(t, NewMemoryStore())
+}
+
+// testStoreList checks the filters of List on an empty store
+func testStoreList(t *testing.T, m TaskStore) {
+	t.Helper()
+	now := time.Now()
+	_ = m.Put(&models.Task{ID: "c", Type: constants.TypePing, Status: constants.StatusDone, CreatedAt: now.Add(2 * time.Second), FinishedAt: now.Add(3 * time.Second)})
+	_ = m.Put(&models.Task{ID: "a", Type: constants.TypePing, Status: constants.StatusPending, CreatedAt: now, Tags: []string{"web"}})
+	_ = m.Put(&models.Task{ID: "b", Type: constants.TypeHTTPStatus, Status: constants.StatusDone, CreatedAt: now.Add(time.Second), FinishedAt: now.Add(5 * time.Second), Tags: []string{"web"}})
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all", Filter{}, []string{"a", "b", "c"}},
+		{"status", Filter{Statuses: []constants.TaskStatus{constants.StatusDone}}, []string{"b", "c"}},
+		{"type", Filter{Type: constants.TypePing}, []string{"a", "c"}},
+		{"tag", Filter{Tag: "web"}, []string{"a", "b"}},
+		{"created range", Filter{CreatedAfter: now.Add(time.Second), CreatedBefore: now.Add(2 * time.Second)}, []string{"b"}},
+		{"limit", Filter{Limit: 2}, []string{"a", "b"}},
+		{"descending", Filter{Descending: true}, []string{"c", "b", "a"}},
+		{"finished", Filter{SortBy: SortFinished}, []string{"a", "c", "b"}},
+		{"finished descending", Filter{SortBy: SortFinished, Descending: true}, []string{"b", "c", "a"}},
+		{"after cursor", Filter{After: &Cursor{Time: now, ID: "a"}, Limit: 1}, []string{"b"}},
+		{"after cursor descending", Filter{Descending: true, After: &Cursor{Time: now.Add(2 * time.Second), ID: "c"}}, []string{"b", "a"}},
+		{"after unfinished", Filter{SortBy: SortFinished, After: &Cursor{ID: "a"}}, []string{"c", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := m.List(tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(list) != len(tt.want) {
+				t.Fatalf("expected %d tasks, got %d", len(tt.want), len(list))
+			}
+			for i, task := range list {
+				if task.ID != tt.want[i] {
+					t.Errorf("expected task %s at %d, got %s", tt.want[i], i, task.ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/artnikel/taskscheduler/models"
+)
+
+// taskFields has the fields of models.Task without its methods
+type taskFields models.Task
+
+// taskRecord is the encoded form of a task, with its error kept as text
+type taskRecord struct {
+	*taskFields
+	Err string `json:"Err,omitempty"`
+}
+
+// encodeTask returns the JSON form of a task
+func encodeTask(task *models.Task) ([]byte, error) {
+	rec := taskRecord{taskFields: (*taskFields)(task)}
+	if task.Err != nil {
+		rec.Err = task.Err.Error()
+	}
+	return json.Marshal(rec)
+}
+
+// decodeTask parses a task written by encodeTask
+func decodeTask(data []byte) (*models.Task, error) {
+	task := &models.Task{}
+	rec := taskRecord{taskFields: (*taskFields)(task)}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	if rec.Err != "" {
+		task.Err = errors.New(rec.Err)
+	}
+	return task, nil
+}
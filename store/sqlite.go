@@ -0,0 +1,313 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+	// registers the pure Go "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+// migrations returns the steps that upgrade the schema one version at a time, the version is kept in PRAGMA user_version
+//
+// Append new steps to the end, never change a step that has been released.
+func migrations() []string {
+	return []string{
+		`CREATE TABLE tasks (
+			id          TEXT PRIMARY KEY,
+			type        TEXT NOT NULL,
+			status      TEXT NOT NULL,
+			priority    INTEGER NOT NULL,
+			schedule_id TEXT NOT NULL DEFAULT '',
+			created_at  INTEGER NOT NULL,
+			finished_at INTEGER NOT NULL DEFAULT 0,
+			data        TEXT NOT NULL
+		);
+		CREATE INDEX tasks_status ON tasks (status);
+		CREATE INDEX tasks_type ON tasks (type);
+		CREATE INDEX tasks_created_at ON tasks (created_at);
+		CREATE INDEX tasks_schedule_id ON tasks (schedule_id) WHERE schedule_id != '';
+		CREATE TABLE task_tags (
+			task_id TEXT NOT NULL,
+			tag     TEXT NOT NULL,
+			PRIMARY KEY (task_id, tag)
+		);
+		CREATE INDEX task_tags_tag ON task_tags (tag);`,
+		`CREATE INDEX tasks_finished_at ON tasks (finished_at);`,
+		`ALTER TABLE tasks ADD COLUMN batch_id TEXT NOT NULL DEFAULT '';
+		CREATE INDEX tasks_batch_id ON tasks (batch_id) WHERE batch_id != '';`,
+	}
+}
+
+// SQLiteStore keeps tasks in a SQLite database for querying past runs
+//
+// The indexed fields are stored as columns and the whole task as JSON in data.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLiteStore opens or creates the database at path and migrates its schema
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	// one connection serializes writes, so transactions never hit SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	st := &SQLiteStore{db: db}
+	if err := st.migrate(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	return st, nil
+}
+
+// migrate applies the migrations newer than the database version
+func (st *SQLiteStore) migrate() error {
+	var version int
+	if err := st.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	steps := migrations()
+	for ; version < len(steps); version++ {
+		tx, err := st.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(steps[version]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version+1, err)
+		}
+		// PRAGMA does not take bind parameters
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database
+func (st *SQLiteStore) Close() error {
+	return st.db.Close()
+}
+
+// Put inserts a task or replaces the task with the same ID
+func (st *SQLiteStore) Put(task *models.Task) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := put(tx, task); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// put writes a task and its tags inside a transaction
+func put(tx *sql.Tx, task *models.Task) error {
+	data, err := encodeTask(task)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO tasks (id, type, status, priority, schedule_id, batch_id, created_at, finished_at, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET type = excluded.type, status = excluded.status,
+			priority = excluded.priority, schedule_id = excluded.schedule_id, batch_id = excluded.batch_id,
+			created_at = excluded.created_at, finished_at = excluded.finished_at, data = excluded.data`,
+		task.ID, task.Type, task.Status, task.Priority, task.ScheduleID, task.BatchID,
+		unixNano(task.CreatedAt), unixNano(task.FinishedAt), string(data))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM task_tags WHERE task_id = ?`, task.ID); err != nil {
+		return err
+	}
+	for _, tag := range task.Tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO task_tags (task_id, tag) VALUES (?, ?)`, task.ID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unixNano returns the column value of a time, the zero time is stored as 0
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// Get returns the task with the given ID or ErrNotFound
+func (st *SQLiteStore) Get(id string) (*models.Task, error) {
+	return get(st.db.QueryRow(`SELECT data FROM tasks WHERE id = ?`, id))
+}
+
+// get decodes the data column of a single row
+func get(row *sql.Row) (*models.Task, error) {
+	var data string
+	if err := row.Scan(&data); errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return decodeTask([]byte(data))
+}
+
+// Update applies fn to the stored task with the given ID or returns ErrNotFound
+func (st *SQLiteStore) Update(id string, fn func(task *models.Task)) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	task, err := get(tx.QueryRow(`SELECT data FROM tasks WHERE id = ?`, id))
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	fn(task)
+	task.ID = id
+	if err := put(tx, task); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// List returns the tasks matching the filter in the order it asks for
+func (st *SQLiteStore) List(filter Filter) ([]*models.Task, error) {
+	where, args := filterClauses(filter)
+	order, after, afterArgs := sortClauses(filter)
+	if after != "" {
+		where = append(where, after)
+		args = append(args, afterArgs...)
+	}
+
+	query := `SELECT data FROM tasks`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + order
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := st.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]*models.Task, 0)
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		task, err := decodeTask([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, task)
+	}
+	return list, rows.Err()
+}
+
+// filterClauses returns the WHERE conditions of a filter, the cursor aside, and their arguments
+func filterClauses(filter Filter) (where []string, args []any) {
+	if len(filter.Statuses) > 0 {
+		where = append(where, "status IN (?"+strings.Repeat(", ?", len(filter.Statuses)-1)+")")
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if filter.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, filter.Type)
+	}
+	if filter.Tag != "" {
+		where = append(where, "id IN (SELECT task_id FROM task_tags WHERE tag = ?)")
+		args = append(args, filter.Tag)
+	}
+	if filter.ScheduleID != "" {
+		where = append(where, "schedule_id = ?")
+		args = append(args, filter.ScheduleID)
+	}
+	if filter.BatchID != "" {
+		where = append(where, "batch_id = ?")
+		args = append(args, filter.BatchID)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.CreatedAfter.UnixNano())
+	}
+	if !filter.CreatedBefore.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, filter.CreatedBefore.UnixNano())
+	}
+	return where, args
+}
+
+// sortClauses returns the ORDER BY clause of a filter and the condition that starts the page after its cursor, empty without one
+func sortClauses(filter Filter) (order, after string, args []any) {
+	column, cmp, direction := "created_at", ">", "ASC"
+	if filter.SortBy == SortFinished {
+		column = "finished_at"
+	}
+	if filter.Descending {
+		cmp, direction = "<", "DESC"
+	}
+	order = fmt.Sprintf("%[1]s %[2]s, id %[2]s", column, direction)
+	if filter.After != nil {
+		key := unixNano(filter.After.Time)
+		after = fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, cmp)
+		args = []any{key, key, filter.After.ID}
+	}
+	return order, after, args
+}
+
+// Delete removes the task with the given ID
+func (st *SQLiteStore) Delete(id string) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM task_tags WHERE task_id = ?`, id); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM tasks WHERE id = ?`, id); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountByStatus returns the number of tasks in every status using the status index
+func (st *SQLiteStore) CountByStatus() (map[constants.TaskStatus]int, error) {
+	rows, err := st.db.Query(`SELECT status, COUNT(*) FROM tasks GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[constants.TaskStatus]int)
+	for rows.Next() {
+		var status constants.TaskStatus
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
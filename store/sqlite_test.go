@@ -0,0 +1,76 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+func openTestSQLite(t *testing.T, path string) *SQLiteStore {
+	t.Helper()
+	st, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	return st
+}
+
+func TestSQLiteStore_List(t *testing.T) {
+	testStoreList(t, openTestSQLite(t, filepath.Join(t.TempDir(), "tasks.db")))
+}
+
+func TestSQLiteStore_PutUpdateDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	st := openTestSQLite(t, path)
+
+	task := &models.Task{
+		ID:        "a",
+		Type:      constants.TypePing,
+		Status:    constants.StatusPending,
+		CreatedAt: time.Now(),
+		Tags:      []string{"web", "nightly"},
+	}
+	if err := st.Put(task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := st.Update("a", func(task *models.Task) {
+		task.Status = constants.StatusFailed
+		task.Err = errors.New("boom")
+		task.Tags = []string{"web"}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := st.Update("missing", func(*models.Task) {}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	// reopening runs the migrations again, which must be a no-op
+	reopened := openTestSQLite(t, path)
+	got, err := reopened.Get("a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status != constants.StatusFailed || got.Err == nil || got.Err.Error() != "boom" {
+		t.Errorf("unexpected task: %+v", got)
+	}
+	if list, _ := reopened.List(Filter{Tag: "nightly"}); len(list) != 0 {
+		t.Errorf("expected removed tag to be gone, got %d tasks", len(list))
+	}
+	counts, _ := reopened.CountByStatus()
+	if counts[constants.StatusFailed] != 1 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+
+	if err := reopened.Delete("a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := reopened.Get("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
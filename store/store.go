@@ -0,0 +1,118 @@
+// Package store persists the tasks tracked by the scheduler
+package store
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+// ErrNotFound is returned when no task has the given ID
+var ErrNotFound = errors.New("task not found in store")
+
+// TaskStore keeps task records by their ID
+//
+// Implementations must be safe for concurrent use. Tasks passed in and
+// returned are copies, so callers may keep or change them freely.
+type TaskStore interface {
+	// Put inserts a task or replaces the task with the same ID
+	Put(task *models.Task) error
+	// Get returns the task with the given ID or ErrNotFound
+	Get(id string) (*models.Task, error)
+	// Update applies fn to the stored task with the given ID or returns ErrNotFound
+	Update(id string, fn func(task *models.Task)) error
+	// List returns the tasks matching the filter in the order it asks for
+	List(filter Filter) ([]*models.Task, error)
+	// Delete removes the task with the given ID, deleting a missing task is not an error
+	Delete(id string) error
+	// CountByStatus returns the number of tasks in every status
+	CountByStatus() (map[constants.TaskStatus]int, error)
+}
+
+// SortField is the task time List orders by, ties are broken by ID
+type SortField string
+
+const (
+	// SortCreated orders tasks by creation time
+	SortCreated SortField = "created_at"
+	// SortFinished orders tasks by finish time, unfinished tasks count as never finished and come first
+	SortFinished SortField = "finished_at"
+)
+
+// Cursor is the sort position of the last task of a page, List continues right after it
+type Cursor struct {
+	Time time.Time
+	ID   string
+}
+
+// Filter selects and orders tasks in List, zero fields match every task
+type Filter struct {
+	// Statuses matches tasks in any of the given statuses
+	Statuses []constants.TaskStatus
+	// Type matches tasks of the given type
+	Type constants.TaskType
+	// Tag matches tasks carrying the given tag
+	Tag string
+	// ScheduleID matches the child tasks of the given schedule
+	ScheduleID string
+	// BatchID matches the tasks submitted in the given batch
+	BatchID string
+	// CreatedAfter matches tasks created at or after the given time
+	CreatedAfter time.Time
+	// CreatedBefore matches tasks created before the given time
+	CreatedBefore time.Time
+	// SortBy is the time tasks are ordered by, SortCreated when empty
+	SortBy SortField
+	// Descending orders the newest tasks first
+	Descending bool
+	// After skips the tasks up to and including the cursor position
+	After *Cursor
+	// Limit caps the number of returned tasks, zero means no limit
+	Limit int
+}
+
+// SortKey returns the time of a task the filter orders by
+func (f *Filter) SortKey(task *models.Task) time.Time {
+	if f.SortBy == SortFinished {
+		return task.FinishedAt
+	}
+	return task.CreatedAt
+}
+
+// CursorOf returns the cursor that continues a listing after the given task
+func (f *Filter) CursorOf(task *models.Task) Cursor {
+	return Cursor{Time: f.SortKey(task), ID: task.ID}
+}
+
+// Less reports whether task a comes before task b in the order of the filter
+func (f *Filter) Less(a, b *models.Task) bool {
+	return f.before(f.CursorOf(a), f.CursorOf(b))
+}
+
+// before reports whether position a comes before position b in the order of the filter
+func (f *Filter) before(a, b Cursor) bool {
+	if !a.Time.Equal(b.Time) {
+		return a.Time.Before(b.Time) != f.Descending
+	}
+	return a.ID != b.ID && (a.ID < b.ID) != f.Descending
+}
+
+// Match reports whether a task passes the filter and comes after its cursor, Limit is not applied
+func (f *Filter) Match(task *models.Task) bool {
+	switch {
+	case len(f.Statuses) > 0 && !slices.Contains(f.Statuses, task.Status),
+		f.Type != "" && task.Type != f.Type,
+		f.Tag != "" && !slices.Contains(task.Tags, f.Tag),
+		f.ScheduleID != "" && task.ScheduleID != f.ScheduleID,
+		f.BatchID != "" && task.BatchID != f.BatchID,
+		!f.CreatedAfter.IsZero() && task.CreatedAt.Before(f.CreatedAfter),
+		!f.CreatedBefore.IsZero() && !task.CreatedAt.Before(f.CreatedBefore),
+		f.After != nil && !f.before(*f.After, f.CursorOf(task)):
+		return false
+	default:
+		return true
+	}
+}
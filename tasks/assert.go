@@ -0,0 +1,370 @@
+package tasks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+// Assertion types of an HTTP task
+const (
+	// AssertBodyContains checks that the body contains Value
+	AssertBodyContains = "body_contains"
+	// AssertBodyMatches checks that the body matches the regular expression Pattern
+	AssertBodyMatches = "body_matches"
+	// AssertJSONEquals checks that the JSON value at Path equals Value
+	AssertJSONEquals = "json_equals"
+	// AssertJSONExists checks that the JSON body has a value at Path
+	AssertJSONExists = "json_exists"
+	// AssertHeaderMatches checks that the header Name matches the regular expression Pattern
+	AssertHeaderMatches = "header_matches"
+	// AssertMaxBodyBytes checks that the body is at most Value bytes long
+	AssertMaxBodyBytes = "max_body_bytes"
+	// AssertMaxResponseTime checks that the response, body included, took at most the duration Value
+	AssertMaxResponseTime = "max_response_time"
+)
+
+// AssertionSpec describes one check on the response of an http_status task
+type AssertionSpec struct {
+	Type string `json:"type" yaml:"type"`
+	// Value is the substring, the expected JSON value, the byte limit or the duration limit, depending on Type
+	Value any `json:"value,omitempty" yaml:"value"`
+	// Pattern is the regular expression of body_matches and header_matches
+	Pattern string `json:"pattern,omitempty" yaml:"pattern"`
+	// Path locates a JSON value, e.g. "$.data.items[0].id"
+	Path string `json:"path,omitempty" yaml:"path"`
+	// Name is the header of header_matches
+	Name string `json:"name,omitempty" yaml:"name"`
+}
+
+// Assertion is a validated check on an HTTP response, see NewAssertion
+type Assertion struct {
+	// description names the check and its arguments in results
+	description string
+	// readsBody is set for checks that need the body in memory
+	readsBody bool
+	// check returns why the response fails the check, empty when it passes
+	check func(r *httpResponse) string
+}
+
+// httpResponse is what assertions check
+type httpResponse struct {
+	header http.Header
+	// body is nil unless an assertion reads it
+	body []byte
+	// size is the length of the whole body, truncated is set when body holds only its start
+	size      int64
+	truncated bool
+	total     time.Duration
+	// parsed caches the decoded JSON body
+	parsed    any
+	parseErr  error
+	parseDone bool
+}
+
+// json returns the decoded JSON body
+func (r *httpResponse) json() (any, error) {
+	if !r.parseDone {
+		r.parseDone = true
+		if r.truncated {
+			r.parseErr = fmt.Errorf("body is larger than %d bytes", constants.HTTPAssertBodyLimit)
+		} else {
+			r.parseErr = json.Unmarshal(r.body, &r.parsed)
+		}
+	}
+	return r.parsed, r.parseErr
+}
+
+// NewAssertion validates an assertion spec
+func NewAssertion(spec AssertionSpec) (Assertion, error) {
+	switch spec.Type {
+	case AssertBodyContains:
+		return newBodyContains(spec)
+	case AssertBodyMatches:
+		return newBodyMatches(spec)
+	case AssertJSONEquals:
+		return newJSONEquals(spec)
+	case AssertJSONExists:
+		return newJSONExists(spec)
+	case AssertHeaderMatches:
+		return newHeaderMatches(spec)
+	case AssertMaxBodyBytes:
+		return newMaxBodyBytes(spec)
+	case AssertMaxResponseTime:
+		return newMaxResponseTime(spec)
+	default:
+		return Assertion{}, fmt.Errorf("unknown assertion type %q", spec.Type)
+	}
+}
+
+// newBodyContains validates a body_contains spec
+func newBodyContains(spec AssertionSpec) (Assertion, error) {
+	want, ok := spec.Value.(string)
+	if !ok || want == "" {
+		return Assertion{}, errors.New("body_contains needs a string value")
+	}
+	return Assertion{
+		description: fmt.Sprintf("body_contains %q", want),
+		readsBody:   true,
+		check: func(r *httpResponse) string {
+			if bytes.Contains(r.body, []byte(want)) {
+				return ""
+			}
+			return truncatedNote(r, "substring not found")
+		},
+	}, nil
+}
+
+// newBodyMatches validates a body_matches spec
+func newBodyMatches(spec AssertionSpec) (Assertion, error) {
+	re, err := compilePattern(spec)
+	if err != nil {
+		return Assertion{}, err
+	}
+	return Assertion{
+		description: fmt.Sprintf("body_matches %q", spec.Pattern),
+		readsBody:   true,
+		check: func(r *httpResponse) string {
+			if re.Match(r.body) {
+				return ""
+			}
+			return truncatedNote(r, "no match")
+		},
+	}, nil
+}
+
+// newJSONEquals validates a json_equals spec
+func newJSONEquals(spec AssertionSpec) (Assertion, error) {
+	path, err := parseJSONPath(spec.Path)
+	if err != nil {
+		return Assertion{}, err
+	}
+	// round trip the expected value so that it compares like a decoded body, e.g. YAML integers become float64
+	encoded, err := json.Marshal(spec.Value)
+	if err != nil {
+		return Assertion{}, fmt.Errorf("invalid json_equals value: %w", err)
+	}
+	var want any
+	_ = json.Unmarshal(encoded, &want)
+	return Assertion{
+		description: fmt.Sprintf("json_equals %s %s", spec.Path, encoded),
+		readsBody:   true,
+		check: func(r *httpResponse) string {
+			doc, err := r.json()
+			if err != nil {
+				return "invalid JSON body: " + err.Error()
+			}
+			got, ok := lookupJSON(doc, path)
+			if !ok {
+				return "no value at path"
+			}
+			if reflect.DeepEqual(got, want) {
+				return ""
+			}
+			actual, _ := json.Marshal(got)
+			return fmt.Sprintf("got %s", actual)
+		},
+	}, nil
+}
+
+// newJSONExists validates a json_exists spec
+func newJSONExists(spec AssertionSpec) (Assertion, error) {
+	path, err := parseJSONPath(spec.Path)
+	if err != nil {
+		return Assertion{}, err
+	}
+	return Assertion{
+		description: fmt.Sprintf("json_exists %s", spec.Path),
+		readsBody:   true,
+		check: func(r *httpResponse) string {
+			doc, err := r.json()
+			if err != nil {
+				return "invalid JSON body: " + err.Error()
+			}
+			if _, ok := lookupJSON(doc, path); !ok {
+				return "no value at path"
+			}
+			return ""
+		},
+	}, nil
+}
+
+// newHeaderMatches validates a header_matches spec
+func newHeaderMatches(spec AssertionSpec) (Assertion, error) {
+	if spec.Name == "" {
+		return Assertion{}, errors.New("header_matches needs a name")
+	}
+	re, err := compilePattern(spec)
+	if err != nil {
+		return Assertion{}, err
+	}
+	return Assertion{
+		description: fmt.Sprintf("header_matches %s %q", http.CanonicalHeaderKey(spec.Name), spec.Pattern),
+		check: func(r *httpResponse) string {
+			values := r.header.Values(spec.Name)
+			for _, value := range values {
+				if re.MatchString(value) {
+					return ""
+				}
+			}
+			if len(values) == 0 {
+				return "header missing"
+			}
+			return fmt.Sprintf("no match in %q", strings.Join(values, ", "))
+		},
+	}, nil
+}
+
+// newMaxBodyBytes validates a max_body_bytes spec
+func newMaxBodyBytes(spec AssertionSpec) (Assertion, error) {
+	limit, ok := wholeNumber(spec.Value)
+	if !ok || limit <= 0 {
+		return Assertion{}, errors.New("max_body_bytes needs a positive whole number")
+	}
+	return Assertion{
+		description: fmt.Sprintf("max_body_bytes %d", limit),
+		check: func(r *httpResponse) string {
+			if r.size <= limit {
+				return ""
+			}
+			return fmt.Sprintf("body is %d bytes", r.size)
+		},
+	}, nil
+}
+
+// newMaxResponseTime validates a max_response_time spec
+func newMaxResponseTime(spec AssertionSpec) (Assertion, error) {
+	value, _ := spec.Value.(string)
+	limit, err := time.ParseDuration(value)
+	if err != nil || limit <= 0 {
+		return Assertion{}, errors.New("max_response_time needs a positive duration such as \"500ms\"")
+	}
+	return Assertion{
+		description: fmt.Sprintf("max_response_time %v", limit),
+		check: func(r *httpResponse) string {
+			if r.total <= limit {
+				return ""
+			}
+			return fmt.Sprintf("response took %v", r.total.Round(time.Millisecond))
+		},
+	}, nil
+}
+
+// checkAssertions runs every assertion against a response
+func checkAssertions(assertions []Assertion, r *httpResponse) (results []models.AssertionResult, failed []string) {
+	for _, a := range assertions {
+		result := models.AssertionResult{Check: a.description, Passed: true}
+		if msg := a.check(r); msg != "" {
+			result.Passed = false
+			result.Error = msg
+			failed = append(failed, a.description+": "+msg)
+		}
+		results = append(results, result)
+	}
+	return results, failed
+}
+
+// compilePattern compiles the regular expression of a spec
+func compilePattern(spec AssertionSpec) (*regexp.Regexp, error) {
+	if spec.Pattern == "" {
+		return nil, fmt.Errorf("%s needs a pattern", spec.Type)
+	}
+	re, err := regexp.Compile(spec.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s pattern: %w", spec.Type, err)
+	}
+	return re, nil
+}
+
+// truncatedNote explains a failed body check that only saw the start of the body
+func truncatedNote(r *httpResponse, msg string) string {
+	if r.truncated {
+		return fmt.Sprintf("%s in the first %d bytes", msg, constants.HTTPAssertBodyLimit)
+	}
+	return msg
+}
+
+// wholeNumber converts a decoded JSON or YAML number to an integer
+func wholeNumber(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		if n != math.Trunc(n) || n > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// parseJSONPath splits a path like "$.data.items[0].id" into object keys and array indexes
+func parseJSONPath(path string) ([]any, error) {
+	p := strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if p == "" {
+		return nil, errors.New("json path is required")
+	}
+	var steps []any
+	for _, part := range strings.Split(p, ".") {
+		key, rest := part, ""
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			key, rest = part[:i], part[i:]
+		}
+		if key == "" && rest == "" {
+			return nil, fmt.Errorf("invalid json path %q", path)
+		}
+		if key != "" {
+			steps = append(steps, key)
+		}
+		for rest != "" {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid json path %q", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index in json path %q", path)
+			}
+			steps = append(steps, index)
+			rest = rest[end+1:]
+		}
+	}
+	return steps, nil
+}
+
+// lookupJSON follows a parsed path through a decoded JSON document
+func lookupJSON(doc any, path []any) (any, bool) {
+	for _, step := range path {
+		switch s := step.(type) {
+		case string:
+			obj, ok := doc.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if doc, ok = obj[s]; !ok {
+				return nil, false
+			}
+		case int:
+			arr, ok := doc.([]any)
+			if !ok || s >= len(arr) {
+				return nil, false
+			}
+			doc = arr[s]
+		}
+	}
+	return doc, true
+}
@@ -0,0 +1,154 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []any
+		wantErr bool
+	}{
+		{"status", []any{"status"}, false},
+		{"$.data.items[0].id", []any{"data", "items", 0, "id"}, false},
+		{"matrix[1][2]", []any{"matrix", 1, 2}, false},
+		{"$", nil, true},
+		{"a..b", nil, true},
+		{"items[x]", nil, true},
+		{"items[-1]", nil, true},
+		{"items[0", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseJSONPath(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: expected error %v, got %v", tt.in, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestNewAssertion_Invalid(t *testing.T) {
+	invalid := []AssertionSpec{
+		{Type: "body_size"},
+		{Type: AssertBodyContains},
+		{Type: AssertBodyContains, Value: 42},
+		{Type: AssertBodyMatches, Pattern: "("},
+		{Type: AssertJSONEquals, Path: "", Value: "up"},
+		{Type: AssertJSONExists, Path: "a[b]"},
+		{Type: AssertHeaderMatches, Pattern: "json"},
+		{Type: AssertMaxBodyBytes, Value: 1.5},
+		{Type: AssertMaxBodyBytes, Value: "1kb"},
+		{Type: AssertMaxResponseTime, Value: 500},
+		{Type: AssertMaxResponseTime, Value: "-1s"},
+	}
+	for _, spec := range invalid {
+		if _, err := NewAssertion(spec); err == nil {
+			t.Errorf("spec %+v: expected error, got nil", spec)
+		}
+	}
+}
+
+// mustAssertions validates assertion specs for a test
+func mustAssertions(t *testing.T, specs ...AssertionSpec) []Assertion {
+	t.Helper()
+	assertions := make([]Assertion, 0, len(specs))
+	for _, spec := range specs {
+		a, err := NewAssertion(spec)
+		if err != nil {
+			t.Fatalf("spec %+v: %v", spec, err)
+		}
+		assertions = append(assertions, a)
+	}
+	return assertions
+}
+
+func TestMakeHTTPTask_Assertions(t *testing.T) {
+	const body = `{"status": "up", "version": "2.4.1", "checks": [{"name": "db", "latency": 3}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	passing := mustAssertions(t,
+		AssertionSpec{Type: AssertBodyContains, Value: `"up"`},
+		AssertionSpec{Type: AssertBodyMatches, Pattern: `"version":\s*"2\.`},
+		AssertionSpec{Type: AssertJSONEquals, Path: "status", Value: "up"},
+		AssertionSpec{Type: AssertJSONEquals, Path: "$.checks[0].latency", Value: 3},
+		AssertionSpec{Type: AssertJSONExists, Path: "checks[0].name"},
+		AssertionSpec{Type: AssertHeaderMatches, Name: "content-type", Pattern: "^application/json"},
+		AssertionSpec{Type: AssertMaxBodyBytes, Value: float64(1024)},
+		AssertionSpec{Type: AssertMaxResponseTime, Value: "5s"},
+	)
+	result, err := MakeHTTPTask(server.URL, HTTPOptions{Assertions: passing})(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(result.Assertions) != len(passing) {
+		t.Fatalf("expected %d assertion results, got %+v", len(passing), result.Assertions)
+	}
+	for _, a := range result.Assertions {
+		if !a.Passed || a.Error != "" {
+			t.Errorf("expected %s to pass, got %+v", a.Check, a)
+		}
+	}
+
+	failing := mustAssertions(t,
+		AssertionSpec{Type: AssertBodyContains, Value: "down"},
+		AssertionSpec{Type: AssertJSONEquals, Path: "status", Value: "up"},
+		AssertionSpec{Type: AssertJSONEquals, Path: "version", Value: "3.0.0"},
+		AssertionSpec{Type: AssertJSONExists, Path: "checks[1]"},
+		AssertionSpec{Type: AssertHeaderMatches, Name: "X-Missing", Pattern: "."},
+		AssertionSpec{Type: AssertMaxBodyBytes, Value: 10},
+		AssertionSpec{Type: AssertMaxResponseTime, Value: "1ns"},
+	)
+	result, err = MakeHTTPTask(server.URL, HTTPOptions{Assertions: failing})(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed 6 of 7 assertions") {
+		t.Fatalf("expected 6 failed assertions, got %v", err)
+	}
+	if result == nil || len(result.Assertions) != len(failing) {
+		t.Fatalf("expected a result listing every assertion, got %+v", result)
+	}
+	wantErrors := []string{"substring not found", "", `got "2.4.1"`, "no value at path", "header missing", fmt.Sprintf("body is %d bytes", len(body)), "response took"}
+	for i, a := range result.Assertions {
+		if a.Passed != (wantErrors[i] == "") || !strings.HasPrefix(a.Error, wantErrors[i]) {
+			t.Errorf("assertion %d: expected error %q, got %+v", i, wantErrors[i], a)
+		}
+	}
+}
+
+func TestMakeHTTPTask_AssertionsInvalidJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("<html>ok</html>"))
+	}))
+	defer server.Close()
+
+	assertions := mustAssertions(t, AssertionSpec{Type: AssertJSONExists, Path: "status"})
+	result, err := MakeHTTPTask(server.URL, HTTPOptions{Assertions: assertions})(context.Background())
+	if err == nil || result == nil || !strings.HasPrefix(result.Assertions[0].Error, "invalid JSON body") {
+		t.Errorf("expected an invalid JSON failure, got %+v, %v", result, err)
+	}
+}
+
+func TestMakeHTTPTask_AssertionsSkippedOnStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	assertions := mustAssertions(t, AssertionSpec{Type: AssertBodyContains, Value: "down"})
+	result, err := MakeHTTPTask(server.URL, HTTPOptions{Assertions: assertions})(context.Background())
+	if err == nil || result == nil || len(result.Assertions) != 0 {
+		t.Errorf("expected a status error without assertion results, got %+v, %v", result, err)
+	}
+}
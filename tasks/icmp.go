@@ -0,0 +1,251 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Protocol numbers of ICMP for IPv4 and IPv6, used to parse replies
+const (
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+// ICMPOptions configures the probes of an ICMP echo task, zero fields take the defaults
+type ICMPOptions struct {
+	// Count is the number of echo requests, one when zero
+	Count int
+	// Interval is the time between the starts of two probes, constants.PingInterval when zero
+	Interval time.Duration
+	// ReplyTimeout is how long a probe waits for its reply, constants.ICMPReplyTimeout when zero
+	ReplyTimeout time.Duration
+	// Source is the local IP address probes are sent from, chosen by the system when empty
+	Source string
+}
+
+// withDefaults fills the zero fields of the options
+func (o ICMPOptions) withDefaults() ICMPOptions {
+	if o.Count <= 0 {
+		o.Count = 1
+	}
+	if o.Interval <= 0 {
+		o.Interval = constants.PingInterval
+	}
+	if o.ReplyTimeout <= 0 {
+		o.ReplyTimeout = constants.ICMPReplyTimeout
+	}
+	return o
+}
+
+// icmpConn is an ICMP endpoint for one address family
+type icmpConn struct {
+	conn *icmp.PacketConn
+	// raw is set for raw sockets, which see every ICMP message of the host and need an echo ID of their own
+	raw bool
+	v6  bool
+}
+
+// MakeICMPPingTask returns a task function that sends ICMP echo requests to the given address
+//
+// It uses an unprivileged ICMP datagram socket when the kernel allows it
+// (net.ipv4.ping_group_range on Linux) and a raw socket otherwise, which needs
+// root or CAP_NET_RAW. Both IPv4 and IPv6 targets are supported. Probes without
+// a reply count as lost, and the task fails only when no probe gets one.
+func MakeICMPPingTask(address string, opts ICMPOptions) func(ctx context.Context) (*models.Result, error) {
+	opts = opts.withDefaults()
+	return func(ctx context.Context) (*models.Result, error) {
+		target, err := resolveTarget(ctx, address, opts.Source)
+		if err != nil {
+			return nil, fmt.Errorf("icmp ping %s failed: %w", address, err)
+		}
+		c, err := listenICMP(target, opts.Source)
+		if err != nil {
+			return nil, fmt.Errorf("icmp ping %s failed: %w", address, err)
+		}
+		defer c.conn.Close()
+		// unblock a pending read when the task is cancelled or times out
+		stop := context.AfterFunc(ctx, func() { _ = c.conn.SetReadDeadline(time.Now()) })
+		defer stop()
+
+		// #nosec G404 -- echo identifiers only need to differ between concurrent tasks
+		id, base := rand.IntN(1<<16), rand.IntN(1<<16)
+		var (
+			rtts    []time.Duration
+			ttl     int
+			lastErr error
+		)
+		first := time.Now()
+		for i := range opts.Count {
+			if i > 0 {
+				timer := time.NewTimer(time.Until(first.Add(time.Duration(i) * opts.Interval)))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, fmt.Errorf("icmp ping %s failed: %w", address, ctx.Err())
+				case <-timer.C:
+				}
+			}
+			rtt, hops, err := c.probe(target, id, (base+i)&0xffff, opts.ReplyTimeout)
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("icmp ping %s failed: %w", address, ctx.Err())
+			}
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			rtts = append(rtts, rtt)
+			ttl = hops
+		}
+		if len(rtts) == 0 {
+			return nil, fmt.Errorf("icmp ping %s failed: %w", address, lastErr)
+		}
+		stats := newPingStats(opts.Count, rtts)
+		socket := "datagram"
+		if c.raw {
+			socket = "raw"
+		}
+		return &models.Result{
+			Summary: fmt.Sprintf("icmp ping %s success, %d probes, %d received, %.1f%% loss, ttl %d, rtt min/avg/max/stddev = %.3f/%.3f/%.3f/%.3f ms",
+				address, opts.Count, len(rtts), stats.LossPercent, ttl, stats.MinMS, stats.AvgMS, stats.MaxMS, stats.StddevMS),
+			LatencyMS:  stats.AvgMS,
+			ResolvedIP: target.String(),
+			Metrics:    stats.metrics(map[string]any{"ttl": ttl, "socket": socket}),
+		}, nil
+	}
+}
+
+// resolveTarget returns the first IP of the address in the family of the source, if any
+func resolveTarget(ctx context.Context, address, source string) (net.IP, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	var from net.IP
+	if source != "" {
+		from = net.ParseIP(source)
+	}
+	for _, addr := range addrs {
+		if from == nil || (from.To4() == nil) == (addr.IP.To4() == nil) {
+			return addr.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("no address of %s in the family of source %s", address, source)
+}
+
+// listenICMP opens a datagram ICMP socket for the family of the target, or a raw one when datagram sockets are not allowed
+func listenICMP(target net.IP, source string) (*icmpConn, error) {
+	v6 := target.To4() == nil
+	datagram, raw, local := "udp4", "ip4:icmp", "0.0.0.0"
+	if v6 {
+		datagram, raw, local = "udp6", "ip6:ipv6-icmp", "::"
+	}
+	if source != "" {
+		local = source
+	}
+	c := &icmpConn{v6: v6}
+	conn, err := icmp.ListenPacket(datagram, local)
+	if err != nil {
+		var rawErr error
+		if conn, rawErr = icmp.ListenPacket(raw, local); rawErr != nil {
+			return nil, fmt.Errorf("no icmp socket allowed: %w", errors.Join(err, rawErr))
+		}
+		c.raw = true
+	}
+	c.conn = conn
+	if v6 {
+		err = conn.IPv6PacketConn().SetControlMessage(ipv6.FlagHopLimit, true)
+	} else {
+		err = conn.IPv4PacketConn().SetControlMessage(ipv4.FlagTTL, true)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// probe sends one echo request and waits for its reply, it returns the round trip time and the TTL of the reply
+func (c *icmpConn) probe(target net.IP, id, seq int, timeout time.Duration) (time.Duration, int, error) {
+	var msgType icmp.Type = ipv4.ICMPTypeEcho
+	replyType, proto := icmp.Type(ipv4.ICMPTypeEchoReply), protocolICMP
+	if c.v6 {
+		msgType, replyType, proto = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, protocolIPv6ICMP
+	}
+	// the kernel computes the ICMPv6 checksum and rewrites the ID of datagram sockets
+	request, err := (&icmp.Message{
+		Type: msgType,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("taskscheduler")},
+	}).Marshal(nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	var dst net.Addr = &net.IPAddr{IP: target}
+	if !c.raw {
+		dst = &net.UDPAddr{IP: target}
+	}
+	sent := time.Now()
+	if err := c.conn.SetReadDeadline(sent.Add(timeout)); err != nil {
+		return 0, 0, err
+	}
+	if _, err := c.conn.WriteTo(request, dst); err != nil {
+		return 0, 0, err
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, ttl, peer, err := c.read(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return 0, 0, fmt.Errorf("no reply to echo %d within %v", seq, timeout)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		reply, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || reply.Type != replyType {
+			continue
+		}
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq || (c.raw && echo.ID != id) || !peerIP(peer).Equal(target) {
+			continue
+		}
+		return time.Since(sent), ttl, nil
+	}
+}
+
+// read receives one ICMP message with the TTL or hop limit it arrived with
+func (c *icmpConn) read(buf []byte) (int, int, net.Addr, error) {
+	if c.v6 {
+		n, cm, peer, err := c.conn.IPv6PacketConn().ReadFrom(buf)
+		if cm != nil {
+			return n, cm.HopLimit, peer, err
+		}
+		return n, 0, peer, err
+	}
+	n, cm, peer, err := c.conn.IPv4PacketConn().ReadFrom(buf)
+	if cm != nil {
+		return n, cm.TTL, peer, err
+	}
+	return n, 0, peer, err
+}
+
+// peerIP returns the IP of the sender of an ICMP message
+func peerIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	default:
+		return nil
+	}
+}
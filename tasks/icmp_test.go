@@ -0,0 +1,66 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runICMP runs an ICMP task and skips the test when the host allows no ICMP socket
+func runICMP(t *testing.T, ctx context.Context, address string, opts ICMPOptions) (map[string]any, error) {
+	t.Helper()
+	result, err := MakeICMPPingTask(address, opts)(ctx)
+	if err != nil && strings.Contains(err.Error(), "no icmp socket allowed") {
+		t.Skipf("icmp sockets unavailable: %v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.Metrics, nil
+}
+
+func TestMakeICMPPingTask(t *testing.T) {
+	for _, address := range []string{"127.0.0.1", "::1"} {
+		t.Run(address, func(t *testing.T) {
+			metrics, err := runICMP(t, context.Background(), address, ICMPOptions{Count: 3, Interval: 10 * time.Millisecond})
+			if err != nil && address == "::1" && strings.Contains(err.Error(), "cannot assign requested address") {
+				t.Skip("no IPv6 loopback")
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if metrics["sent"] != 3 || metrics["received"] != 3 || metrics["loss_percent"] != 0.0 {
+				t.Errorf("unexpected metrics: %v", metrics)
+			}
+			if ttl, _ := metrics["ttl"].(int); ttl <= 0 {
+				t.Errorf("expected a TTL, got %v", metrics["ttl"])
+			}
+			if socket := metrics["socket"]; socket != "datagram" && socket != "raw" {
+				t.Errorf("unexpected socket %v", socket)
+			}
+		})
+	}
+}
+
+func TestMakeICMPPingTask_NoReply(t *testing.T) {
+	// 198.51.100.0/24 is reserved for documentation and never answers
+	_, err := runICMP(t, context.Background(), "198.51.100.1", ICMPOptions{Count: 2, Interval: 10 * time.Millisecond, ReplyTimeout: 20 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "icmp ping 198.51.100.1 failed") {
+		t.Errorf("expected failure, got %v", err)
+	}
+}
+
+func TestMakeICMPPingTask_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := runICMP(t, ctx, "127.0.0.1", ICMPOptions{Count: 10, Interval: time.Second})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the task to stop with its context, took %v", elapsed)
+	}
+}
@@ -2,23 +2,162 @@
 package tasks
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net"
+	"strconv"
 	"time"
 
 	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
 )
 
+// PingOptions configures the probes of a ping task, zero fields take the defaults
+type PingOptions struct {
+	// Port is the TCP port dialed, constants.PingPort when zero
+	Port int
+	// Count is the number of probes, one when zero
+	Count int
+	// Interval is the time between the starts of two probes, constants.PingInterval when zero
+	Interval time.Duration
+	// Source is the local IP address probes are sent from, chosen by the system when empty
+	Source string
+}
+
+// withDefaults fills the zero fields of the options
+func (o PingOptions) withDefaults() PingOptions {
+	if o.Port == 0 {
+		o.Port = constants.PingPort
+	}
+	if o.Count <= 0 {
+		o.Count = 1
+	}
+	if o.Interval <= 0 {
+		o.Interval = constants.PingInterval
+	}
+	return o
+}
+
 // MakePingTask returns a task function that pings the given address over TCP
-func MakePingTask(address string) func() (string, error) {
-	return func() (string, error) {
-		start := time.Now()
-		conn, err := net.DialTimeout("tcp", net.JoinHostPort(address, "80"), constants.TaskTimeout)
-		elapsed := time.Since(start)
-		if err != nil {
-			return "", fmt.Errorf("ping %s failed: %w", address, err)
+func MakePingTask(address string) func(ctx context.Context) (*models.Result, error) {
+	return MakePingTaskWithOptions(address, PingOptions{})
+}
+
+// MakePingTaskWithOptions returns a task function that measures the TCP connect time to the given address
+//
+// Every probe opens and closes one connection. A probe waits for its connection
+// until the deadline of the task, and no longer than the interval when more
+// probes follow. Probes that fail or time out count as lost, and the task fails
+// only when no probe succeeds.
+func MakePingTaskWithOptions(address string, opts PingOptions) func(ctx context.Context) (*models.Result, error) {
+	opts = opts.withDefaults()
+	target := net.JoinHostPort(address, strconv.Itoa(opts.Port))
+	return func(ctx context.Context) (*models.Result, error) {
+		var dialer net.Dialer
+		if opts.Source != "" {
+			dialer.LocalAddr = &net.TCPAddr{IP: net.ParseIP(opts.Source)}
+		}
+		var (
+			rtts       []time.Duration
+			resolvedIP string
+			lastErr    error
+		)
+		first := time.Now()
+		for i := range opts.Count {
+			if i > 0 {
+				timer := time.NewTimer(time.Until(first.Add(time.Duration(i) * opts.Interval)))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, fmt.Errorf("ping %s failed: %w", address, ctx.Err())
+				case <-timer.C:
+				}
+			}
+			probeCtx, cancel := ctx, context.CancelFunc(func() {})
+			if i < opts.Count-1 {
+				probeCtx, cancel = context.WithTimeout(ctx, opts.Interval)
+			}
+			start := time.Now()
+			conn, err := dialer.DialContext(probeCtx, "tcp", target)
+			elapsed := time.Since(start)
+			cancel()
+			if err != nil {
+				if errors.Is(ctx.Err(), context.Canceled) {
+					return nil, fmt.Errorf("ping %s failed: %w", address, err)
+				}
+				lastErr = err
+				continue
+			}
+			resolvedIP = remoteIP(conn.RemoteAddr())
+			_ = conn.Close()
+			rtts = append(rtts, elapsed)
+		}
+		if len(rtts) == 0 {
+			return nil, fmt.Errorf("ping %s failed: %w", address, lastErr)
 		}
-		_ = conn.Close()
-		return fmt.Sprintf("ping %s success, time: %v", address, elapsed), nil
+		stats := newPingStats(opts.Count, rtts)
+		return &models.Result{
+			Summary: fmt.Sprintf("ping %s success, port %d, %d probes, %d received, %.1f%% loss, rtt min/avg/max/stddev = %.3f/%.3f/%.3f/%.3f ms",
+				address, opts.Port, opts.Count, len(rtts), stats.LossPercent, stats.MinMS, stats.AvgMS, stats.MaxMS, stats.StddevMS),
+			LatencyMS:  stats.AvgMS,
+			ResolvedIP: resolvedIP,
+			Metrics:    stats.metrics(map[string]any{"port": opts.Port}),
+		}, nil
+	}
+}
+
+// pingStats summarizes the round trip times of a ping task like the ping command does
+type pingStats struct {
+	Sent        int
+	Received    int
+	LossPercent float64
+	MinMS       float64
+	AvgMS       float64
+	MaxMS       float64
+	StddevMS    float64
+}
+
+// newPingStats computes the statistics of the received probes out of sent
+func newPingStats(sent int, rtts []time.Duration) pingStats {
+	stats := pingStats{
+		Sent:        sent,
+		Received:    len(rtts),
+		LossPercent: float64(sent-len(rtts)) / float64(sent) * 100,
+		MinMS:       math.Inf(1),
+	}
+	var sum, squares float64
+	for _, rtt := range rtts {
+		ms := models.Milliseconds(rtt)
+		stats.MinMS = math.Min(stats.MinMS, ms)
+		stats.MaxMS = math.Max(stats.MaxMS, ms)
+		sum += ms
+		squares += ms * ms
+	}
+	n := float64(len(rtts))
+	stats.AvgMS = sum / n
+	// population deviation, the mdev of ping
+	stats.StddevMS = math.Sqrt(math.Max(squares/n-stats.AvgMS*stats.AvgMS, 0))
+	return stats
+}
+
+// metrics adds the statistics to the other metrics of a result
+func (s pingStats) metrics(extra map[string]any) map[string]any {
+	extra["sent"] = s.Sent
+	extra["received"] = s.Received
+	extra["loss_percent"] = s.LossPercent
+	extra["min_ms"] = s.MinMS
+	extra["avg_ms"] = s.AvgMS
+	extra["max_ms"] = s.MaxMS
+	extra["stddev_ms"] = s.StddevMS
+	return extra
+}
+
+// remoteIP returns the IP of a connection peer, or an empty string when the address has none
+func remoteIP(addr net.Addr) string {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP.String()
 	}
+	return ""
 }
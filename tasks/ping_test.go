@@ -1,26 +1,31 @@
 package tasks
 
 import (
+	"context"
+	"errors"
+	"math"
+	"net"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMakePingTask_Success(t *testing.T) {
 	task := MakePingTask("google.com")
-	result, err := task()
+	result, err := task(context.Background())
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
-	if !strings.Contains(result, "ping google.com success") {
+	if !strings.Contains(result.Summary, "ping google.com success") {
 		t.Errorf("unexpected result: %v", result)
 	}
 }
 
 func TestMakePingTask_Failure(t *testing.T) {
 	task := MakePingTask("nonexistent.domain.local")
-	_, err := task()
+	_, err := task(context.Background())
 
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -30,3 +35,91 @@ func TestMakePingTask_Failure(t *testing.T) {
 		t.Errorf("unexpected error message: %v", err)
 	}
 }
+
+func TestMakePingTask_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	task := MakePingTask("127.0.0.1")
+	_, err := task(ctx)
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// listenLocal starts a TCP listener on a free local port that accepts and closes connections
+func listenLocal(t *testing.T) (net.Listener, int) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	return ln, ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestMakePingTaskWithOptions(t *testing.T) {
+	ln, port := listenLocal(t)
+	defer ln.Close()
+
+	task := MakePingTaskWithOptions("127.0.0.1", PingOptions{Port: port, Count: 3, Interval: 20 * time.Millisecond, Source: "127.0.0.1"})
+	start := time.Now()
+	result, err := task(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected probes spaced by the interval, took %v", elapsed)
+	}
+	if result.Metrics["sent"] != 3 || result.Metrics["received"] != 3 || result.Metrics["loss_percent"] != 0.0 || result.Metrics["port"] != port {
+		t.Errorf("unexpected metrics: %v", result.Metrics)
+	}
+	if result.ResolvedIP != "127.0.0.1" || result.LatencyMS != result.Metrics["avg_ms"] {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if !strings.Contains(result.Summary, "3 probes, 3 received, 0.0% loss") {
+		t.Errorf("unexpected summary: %s", result.Summary)
+	}
+}
+
+func TestMakePingTaskWithOptions_Loss(t *testing.T) {
+	ln, port := listenLocal(t)
+	time.AfterFunc(75*time.Millisecond, func() { _ = ln.Close() })
+
+	task := MakePingTaskWithOptions("127.0.0.1", PingOptions{Port: port, Count: 4, Interval: 50 * time.Millisecond})
+	result, err := task(context.Background())
+	if err != nil {
+		t.Fatalf("expected partial loss to succeed, got %v", err)
+	}
+	if result.Metrics["received"] != 2 || result.Metrics["loss_percent"] != 50.0 {
+		t.Errorf("unexpected metrics: %v", result.Metrics)
+	}
+
+	// every probe lost
+	task = MakePingTaskWithOptions("127.0.0.1", PingOptions{Port: port, Count: 2, Interval: 10 * time.Millisecond})
+	if _, err := task(context.Background()); err == nil || !strings.Contains(err.Error(), "ping 127.0.0.1 failed") {
+		t.Errorf("expected failure, got %v", err)
+	}
+}
+
+func TestNewPingStats(t *testing.T) {
+	stats := newPingStats(4, []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond})
+	if stats.Received != 3 || stats.LossPercent != 25 || stats.MinMS != 1 || stats.AvgMS != 2 || stats.MaxMS != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if math.Abs(stats.StddevMS-math.Sqrt(2.0/3)) > 1e-9 {
+		t.Errorf("expected stddev %v, got %v", math.Sqrt(2.0/3), stats.StddevMS)
+	}
+}
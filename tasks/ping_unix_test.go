@@ -0,0 +1,89 @@
+//go:build unix
+
+package tasks
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// silentListener returns the port of a local listener with a full accept queue, connects to it are never answered
+func silentListener(t *testing.T) int {
+	t.Helper()
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatalf("failed to open socket: %v", err)
+	}
+	t.Cleanup(func() { _ = syscall.Close(fd) })
+	if err := syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		t.Fatalf("failed to bind socket: %v", err)
+	}
+	// a backlog of zero queues one connection, the SYNs of the next ones are dropped
+	if err := syscall.Listen(fd, 0); err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr, err := syscall.Getsockname(fd)
+	if err != nil {
+		t.Fatalf("failed to get socket address: %v", err)
+	}
+	return addr.(*syscall.SockaddrInet4).Port
+}
+
+// fillQueue takes the one place in the accept queue of a silent listener
+func fillQueue(t *testing.T, port int) {
+	t.Helper()
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Fatalf("failed to fill the accept queue: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+}
+
+func TestMakePingTaskWithOptions_ProbeTimeout(t *testing.T) {
+	port := silentListener(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// the first probe fills the accept queue, the next ones are never answered
+	task := MakePingTaskWithOptions("127.0.0.1", PingOptions{Port: port, Count: 3, Interval: 200 * time.Millisecond})
+	result, err := task(ctx)
+	if err != nil {
+		t.Fatalf("expected the unanswered probes to count as lost, got %v", err)
+	}
+	if result.Metrics["sent"] != 3 || result.Metrics["received"] != 1 {
+		t.Errorf("expected 3 sent and 1 received, got %v", result.Metrics)
+	}
+}
+
+func TestMakePingTaskWithOptions_NoAnswer(t *testing.T) {
+	port := silentListener(t)
+	fillQueue(t, port)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 600*time.Millisecond)
+	defer cancel()
+	task := MakePingTaskWithOptions("127.0.0.1", PingOptions{Port: port, Count: 2, Interval: 200 * time.Millisecond})
+	result, err := task(ctx)
+	if err == nil || result != nil {
+		t.Fatalf("expected an error without result, got %+v, %v", result, err)
+	}
+}
+
+func TestMakePingTaskWithOptions_WaitsForDeadline(t *testing.T) {
+	port := silentListener(t)
+	fillQueue(t, port)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := MakePingTaskWithOptions("127.0.0.1", PingOptions{Port: port})(ctx)
+	if elapsed := time.Since(start); elapsed < 2*time.Second {
+		t.Errorf("expected a single probe to wait for the task deadline, gave up after %v", elapsed)
+	}
+	if err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
@@ -0,0 +1,222 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+	"golang.org/x/net/http/httpguts"
+)
+
+// Spec describes a task by its type and target
+type Spec struct {
+	Type    constants.TaskType `json:"type" yaml:"type"`
+	Address string             `json:"address,omitempty" yaml:"address"`
+	URL     string             `json:"url,omitempty" yaml:"url"`
+
+	// Port, Count, ProbeInterval and Source configure ping tasks, see PingOptions,
+	// icmp tasks take the same settings except Port, see ICMPOptions
+	Port          int    `json:"port,omitempty" yaml:"port"`
+	Count         int    `json:"count,omitempty" yaml:"count"`
+	ProbeInterval string `json:"probe_interval,omitempty" yaml:"probe_interval"`
+	Source        string `json:"source,omitempty" yaml:"source"`
+
+	// ServerName, MinDaysValid and AllowInvalidChain configure tls tasks along
+	// with Port, see TLSOptions. MinDaysValid is a pointer so that an explicit
+	// zero turns the threshold off instead of taking the default
+	ServerName        string `json:"server_name,omitempty" yaml:"server_name"`
+	MinDaysValid      *int   `json:"min_days_valid,omitempty" yaml:"min_days_valid"`
+	AllowInvalidChain bool   `json:"allow_invalid_chain,omitempty" yaml:"allow_invalid_chain"`
+
+	// HTTPSpec configures http_status tasks
+	HTTPSpec `yaml:",inline"`
+}
+
+// HTTPSpec describes the request and the expected response of an http_status task, see HTTPOptions
+type HTTPSpec struct {
+	Method      string            `json:"method,omitempty" yaml:"method"`
+	Headers     map[string]string `json:"headers,omitempty" yaml:"headers"`
+	Body        string            `json:"body,omitempty" yaml:"body"`
+	BasicAuth   *BasicAuth        `json:"basic_auth,omitempty" yaml:"basic_auth"`
+	BearerToken string            `json:"bearer_token,omitempty" yaml:"bearer_token"`
+	// Redirects is follow (default) or none
+	Redirects    string `json:"redirects,omitempty" yaml:"redirects"`
+	MaxRedirects int    `json:"max_redirects,omitempty" yaml:"max_redirects"`
+	// ExpectedStatus holds status codes ("200"), classes ("2xx") or ranges ("200-299")
+	ExpectedStatus []string `json:"expected_status,omitempty" yaml:"expected_status"`
+	// Assertions check the content of a response with an expected status
+	Assertions []AssertionSpec `json:"assertions,omitempty" yaml:"assertions"`
+}
+
+// BasicAuth holds the credentials of HTTP basic authentication
+type BasicAuth struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// httpMethod reports whether an http_status task may send a method
+func httpMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// Build validates the spec and returns the task function it describes
+func (s *Spec) Build() (func(ctx context.Context) (*models.Result, error), error) {
+	switch s.Type {
+	case constants.TypePing:
+		if s.Address == "" {
+			return nil, errors.New("address is required")
+		}
+		opts, err := s.pingOptions()
+		if err != nil {
+			return nil, err
+		}
+		return MakePingTaskWithOptions(s.Address, opts), nil
+	case constants.TypeICMP:
+		if s.Address == "" {
+			return nil, errors.New("address is required")
+		}
+		if s.Port != 0 {
+			return nil, errors.New("port is not used by icmp tasks")
+		}
+		opts, err := s.pingOptions()
+		if err != nil {
+			return nil, err
+		}
+		return MakeICMPPingTask(s.Address, ICMPOptions{Count: opts.Count, Interval: opts.Interval, Source: opts.Source}), nil
+	case constants.TypeTLS:
+		if s.Address == "" {
+			return nil, errors.New("address is required")
+		}
+		if s.Port < 0 || s.Port > 65535 {
+			return nil, errors.New("port must be between 1 and 65535")
+		}
+		if s.MinDaysValid != nil && *s.MinDaysValid < 0 {
+			return nil, errors.New("min_days_valid must not be negative")
+		}
+		return MakeTLSCertTask(s.Address, TLSOptions{
+			Port:              s.Port,
+			ServerName:        s.ServerName,
+			MinDaysValid:      s.MinDaysValid,
+			AllowInvalidChain: s.AllowInvalidChain,
+		}), nil
+	case constants.TypeHTTPStatus:
+		if s.URL == "" {
+			return nil, errors.New("url is required")
+		}
+		opts, err := s.httpOptions()
+		if err != nil {
+			return nil, err
+		}
+		return MakeHTTPTask(s.URL, opts), nil
+	default:
+		return nil, fmt.Errorf("unknown task type %q", s.Type)
+	}
+}
+
+// MinTimeout returns the run time limit the task needs when it is longer than constants.TaskTimeout, zero otherwise
+func (s *Spec) MinTimeout() time.Duration {
+	if s.Type != constants.TypePing && s.Type != constants.TypeICMP {
+		return 0
+	}
+	opts, err := s.pingOptions()
+	if err != nil {
+		return 0
+	}
+	opts = opts.withDefaults()
+	if opts.Count == 1 {
+		return 0
+	}
+	spacing := opts.Interval
+	if s.Type == constants.TypeICMP {
+		// a probe may wait for its reply past the start of the next one
+		spacing = max(spacing, constants.ICMPReplyTimeout)
+	}
+	return time.Duration(opts.Count-1)*spacing + constants.TaskTimeout
+}
+
+// pingOptions validates the ping settings of the spec
+func (s *Spec) pingOptions() (PingOptions, error) {
+	opts := PingOptions{Port: s.Port, Count: s.Count, Source: s.Source}
+	if s.Port < 0 || s.Port > 65535 {
+		return opts, errors.New("port must be between 1 and 65535")
+	}
+	if s.Count < 0 || s.Count > constants.MaxPingCount {
+		return opts, fmt.Errorf("count must be between 1 and %d", constants.MaxPingCount)
+	}
+	if s.ProbeInterval != "" {
+		interval, err := time.ParseDuration(s.ProbeInterval)
+		if err != nil || interval <= 0 {
+			return opts, fmt.Errorf("invalid probe_interval %q", s.ProbeInterval)
+		}
+		opts.Interval = interval
+	}
+	if s.Source != "" && net.ParseIP(s.Source) == nil {
+		return opts, fmt.Errorf("source must be an IP address, got %q", s.Source)
+	}
+	return opts, nil
+}
+
+// httpOptions validates the request settings of the spec
+func (s *Spec) httpOptions() (HTTPOptions, error) {
+	opts := HTTPOptions{
+		Method:       strings.ToUpper(s.Method),
+		Body:         s.Body,
+		BearerToken:  s.BearerToken,
+		Redirects:    s.Redirects,
+		MaxRedirects: s.MaxRedirects,
+	}
+	if opts.Method != "" && !httpMethod(opts.Method) {
+		return opts, fmt.Errorf("unsupported method %q", s.Method)
+	}
+	if len(s.Headers) > 0 {
+		opts.Header = make(http.Header, len(s.Headers))
+		for key, value := range s.Headers {
+			if !httpguts.ValidHeaderFieldName(key) || !httpguts.ValidHeaderFieldValue(value) {
+				return opts, fmt.Errorf("invalid header %q", key)
+			}
+			opts.Header.Set(key, value)
+		}
+	}
+	if s.BasicAuth != nil {
+		if s.BasicAuth.Username == "" {
+			return opts, errors.New("basic_auth needs a username")
+		}
+		if s.BearerToken != "" {
+			return opts, errors.New("basic_auth and bearer_token are mutually exclusive")
+		}
+		opts.Username, opts.Password = s.BasicAuth.Username, s.BasicAuth.Password
+	}
+	if s.Redirects != "" && s.Redirects != RedirectFollow && s.Redirects != RedirectNone {
+		return opts, fmt.Errorf("redirects must be %s or %s", RedirectFollow, RedirectNone)
+	}
+	if s.MaxRedirects < 0 || s.MaxRedirects > constants.MaxHTTPRedirects {
+		return opts, fmt.Errorf("max_redirects must be between 1 and %d", constants.MaxHTTPRedirects)
+	}
+	for _, status := range s.ExpectedStatus {
+		r, err := ParseStatusRange(status)
+		if err != nil {
+			return opts, err
+		}
+		opts.ExpectedStatus = append(opts.ExpectedStatus, r)
+	}
+	for i, spec := range s.Assertions {
+		a, err := NewAssertion(spec)
+		if err != nil {
+			return opts, fmt.Errorf("assertion %d: %w", i, err)
+		}
+		opts.Assertions = append(opts.Assertions, a)
+	}
+	return opts, nil
+}
@@ -0,0 +1,85 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+)
+
+func TestSpecBuild(t *testing.T) {
+	valid := []Spec{
+		{Type: constants.TypePing, Address: "example.com"},
+		{Type: constants.TypeHTTPStatus, URL: "http://example.com"},
+		{Type: constants.TypePing, Address: "example.com", Port: 443, Count: 5, ProbeInterval: "200ms", Source: "10.0.0.1"},
+		{Type: constants.TypeICMP, Address: "example.com", Count: 5, ProbeInterval: "200ms"},
+		{Type: constants.TypeTLS, Address: "example.com"},
+		{Type: constants.TypeTLS, Address: "10.0.0.1", Port: 8443, ServerName: "example.com", MinDaysValid: days(30), AllowInvalidChain: true},
+		{Type: constants.TypeTLS, Address: "example.com", MinDaysValid: days(0)},
+		{Type: constants.TypeHTTPStatus, URL: "http://example.com", HTTPSpec: HTTPSpec{
+			Method:         "post",
+			Headers:        map[string]string{"Content-Type": "application/json"},
+			Body:           `{"ok":true}`,
+			BasicAuth:      &BasicAuth{Username: "user", Password: "secret"},
+			Redirects:      RedirectNone,
+			ExpectedStatus: []string{"200", "3xx", "401-403"},
+		}},
+		{Type: constants.TypeHTTPStatus, URL: "http://example.com", HTTPSpec: HTTPSpec{Assertions: []AssertionSpec{
+			{Type: AssertJSONEquals, Path: "status", Value: "up"},
+			{Type: AssertMaxResponseTime, Value: "500ms"},
+		}}},
+	}
+	for _, spec := range valid {
+		fn, err := spec.Build()
+		if err != nil || fn == nil {
+			t.Errorf("spec %+v: expected task function, got error %v", spec, err)
+		}
+	}
+
+	invalid := []Spec{
+		{Type: constants.TypePing},
+		{Type: constants.TypeHTTPStatus},
+		{Type: "unknown", Address: "example.com"},
+		{Type: constants.TypePing, Address: "example.com", Port: 70000},
+		{Type: constants.TypePing, Address: "example.com", Count: constants.MaxPingCount + 1},
+		{Type: constants.TypePing, Address: "example.com", ProbeInterval: "fast"},
+		{Type: constants.TypePing, Address: "example.com", Source: "localhost"},
+		{Type: constants.TypeICMP},
+		{Type: constants.TypeICMP, Address: "example.com", Port: 80},
+		{Type: constants.TypeTLS},
+		{Type: constants.TypeTLS, Address: "example.com", Port: 70000},
+		{Type: constants.TypeTLS, Address: "example.com", MinDaysValid: days(-1)},
+		{Type: constants.TypeHTTPStatus, URL: "http://example.com", HTTPSpec: HTTPSpec{Method: "FETCH"}},
+		{Type: constants.TypeHTTPStatus, URL: "http://example.com", HTTPSpec: HTTPSpec{Headers: map[string]string{"Bad Header": "x"}}},
+		{Type: constants.TypeHTTPStatus, URL: "http://example.com", HTTPSpec: HTTPSpec{Headers: map[string]string{"X-Test": "a\nb"}}},
+		{Type: constants.TypeHTTPStatus, URL: "http://example.com", HTTPSpec: HTTPSpec{BasicAuth: &BasicAuth{Password: "secret"}}},
+		{Type: constants.TypeHTTPStatus, URL: "http://example.com", HTTPSpec: HTTPSpec{BasicAuth: &BasicAuth{Username: "user"}, BearerToken: "token"}},
+		{Type: constants.TypeHTTPStatus, URL: "http://example.com", HTTPSpec: HTTPSpec{Redirects: "sometimes"}},
+		{Type: constants.TypeHTTPStatus, URL: "http://example.com", HTTPSpec: HTTPSpec{MaxRedirects: constants.MaxHTTPRedirects + 1}},
+		{Type: constants.TypeHTTPStatus, URL: "http://example.com", HTTPSpec: HTTPSpec{ExpectedStatus: []string{"ok"}}},
+		{Type: constants.TypeHTTPStatus, URL: "http://example.com", HTTPSpec: HTTPSpec{Assertions: []AssertionSpec{{Type: AssertBodyMatches}}}},
+	}
+	for _, spec := range invalid {
+		if _, err := spec.Build(); err == nil {
+			t.Errorf("spec %+v: expected error, got nil", spec)
+		}
+	}
+}
+
+func TestSpecMinTimeout(t *testing.T) {
+	tests := []struct {
+		spec Spec
+		want time.Duration
+	}{
+		{Spec{Type: constants.TypePing, Address: "example.com"}, 0},
+		{Spec{Type: constants.TypePing, Address: "example.com", Count: 3}, 2*constants.PingInterval + constants.TaskTimeout},
+		{Spec{Type: constants.TypePing, Address: "example.com", Count: 5, ProbeInterval: "100ms"}, 400*time.Millisecond + constants.TaskTimeout},
+		{Spec{Type: constants.TypeICMP, Address: "example.com", Count: 3, ProbeInterval: "100ms"}, 2*constants.ICMPReplyTimeout + constants.TaskTimeout},
+		{Spec{Type: constants.TypeHTTPStatus, URL: "http://example.com"}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.spec.MinTimeout(); got != tt.want {
+			t.Errorf("spec %+v: expected %v, got %v", tt.spec, tt.want, got)
+		}
+	}
+}
@@ -1,26 +1,157 @@
 package tasks
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
 )
 
+// Redirect policies of an HTTP task
+const (
+	// RedirectFollow follows up to MaxRedirects redirects and checks the final response
+	RedirectFollow = "follow"
+	// RedirectNone checks the redirect response itself
+	RedirectNone = "none"
+)
+
+// StatusRange is an inclusive range of HTTP status codes
+type StatusRange struct {
+	Min, Max int
+}
+
+// ParseStatusRange parses a status code ("200"), a class ("2xx") or a range ("200-299")
+func ParseStatusRange(s string) (StatusRange, error) {
+	var r StatusRange
+	var err error
+	switch {
+	case len(s) == 3 && strings.EqualFold(s[1:], "xx"):
+		if s[0] < '1' || s[0] > '5' {
+			return r, fmt.Errorf("invalid status class %q", s)
+		}
+		r.Min = int(s[0]-'0') * 100
+		r.Max = r.Min + 99
+	case strings.Contains(s, "-"):
+		low, high, _ := strings.Cut(s, "-")
+		r.Min, err = strconv.Atoi(low)
+		if err == nil {
+			r.Max, err = strconv.Atoi(high)
+		}
+		if err != nil || r.Min > r.Max {
+			return r, fmt.Errorf("invalid status range %q", s)
+		}
+	default:
+		if r.Min, err = strconv.Atoi(s); err != nil {
+			return r, fmt.Errorf("invalid status code %q", s)
+		}
+		r.Max = r.Min
+	}
+	if r.Min < 100 || r.Max > 599 {
+		return r, fmt.Errorf("status %q is out of the range 100-599", s)
+	}
+	return r, nil
+}
+
+// Contains reports whether a status code is in the range
+func (r StatusRange) Contains(code int) bool {
+	return code >= r.Min && code <= r.Max
+}
+
+// HTTPOptions configures the request of an HTTP task, zero fields take the defaults
+type HTTPOptions struct {
+	// Method is the request method, GET when empty
+	Method string
+	// Header is sent with the request, a Host entry sets the request host
+	Header http.Header
+	// Body is the request body
+	Body string
+	// Username and Password set basic authentication when Username is not empty
+	Username string
+	Password string
+	// BearerToken sets bearer authentication when not empty
+	BearerToken string
+	// Redirects is RedirectFollow (default) or RedirectNone
+	Redirects string
+	// MaxRedirects limits the redirects followed, constants.HTTPMaxRedirects when zero
+	MaxRedirects int
+	// ExpectedStatus lists the status codes counted as success, any code below 400 when empty
+	ExpectedStatus []StatusRange
+	// Assertions are checked against a response with an expected status, the task fails when any does not pass
+	Assertions []Assertion
+}
+
+// withDefaults fills the zero fields of the options
+func (o HTTPOptions) withDefaults() HTTPOptions {
+	if o.Method == "" {
+		o.Method = http.MethodGet
+	}
+	if o.Redirects == "" {
+		o.Redirects = RedirectFollow
+	}
+	if o.MaxRedirects <= 0 {
+		o.MaxRedirects = constants.HTTPMaxRedirects
+	}
+	return o
+}
+
+// expected reports whether a status code counts as success
+func (o HTTPOptions) expected(code int) bool {
+	if len(o.ExpectedStatus) == 0 {
+		return code < http.StatusBadRequest
+	}
+	for _, r := range o.ExpectedStatus {
+		if r.Contains(code) {
+			return true
+		}
+	}
+	return false
+}
+
 // MakeGetStatusTask returns a task that sends an HTTP GET request to the given URL.
-func MakeGetStatusTask(url string) func() (string, error) {
-	return func() (string, error) {
-		client := &http.Client{
-			Timeout: constants.TaskTimeout,
+func MakeGetStatusTask(url string) func(ctx context.Context) (*models.Result, error) {
+	return MakeHTTPTask(url, HTTPOptions{})
+}
+
+// MakeHTTPTask returns a task that sends an HTTP request to the given URL and checks the response status
+//
+// A run with a status that is not expected returns its result along with the
+// error. Otherwise, when the options hold assertions, the outcome of each is
+// listed in the result, and a run that fails an assertion returns its result
+// along with the error, which names every failed check.
+//
+// Every run opens new connections so that its metrics time every phase:
+// dns_ms, connect_ms and tls_ms add up the lookups, connects and handshakes of
+// the run including redirects, ttfb_ms is the time to the first byte of the
+// final response and total_ms the time to the end of its body.
+func MakeHTTPTask(url string, opts HTTPOptions) func(ctx context.Context) (*models.Result, error) {
+	opts = opts.withDefaults()
+	method := strings.ToLower(opts.Method)
+	return func(ctx context.Context) (*models.Result, error) {
+		timing := &httpTiming{connects: make(map[string]time.Time)}
+		req, err := opts.buildRequest(httptrace.WithClientTrace(ctx, timing.trace()), url)
+		if err != nil {
+			return nil, fmt.Errorf("http %s %s failed: %w", method, url, err)
 		}
+		redirects := 0
+		client := opts.newHTTPClient(&redirects)
+		defer client.CloseIdleConnections()
+
 		start := time.Now()
-		resp, err := client.Get(url)
+		resp, err := client.Do(req)
 		elapsed := time.Since(start)
 
 		if err != nil {
-			return "", fmt.Errorf("http get %s failed: %w", url, err)
+			return nil, fmt.Errorf("http %s %s failed: %w", method, url, err)
 		}
 		defer func() {
 			if err := resp.Body.Close(); err != nil {
@@ -28,10 +159,203 @@ func MakeGetStatusTask(url string) func() (string, error) {
 			}
 		}()
 
-		if resp.StatusCode >= http.StatusBadRequest {
-			return "", fmt.Errorf("http get %s returned error status: %d", url, resp.StatusCode)
+		checked := &httpResponse{header: resp.Header}
+		if err := readBody(resp.Body, checked, opts.readsBody()); err != nil {
+			return nil, fmt.Errorf("http %s %s failed to read body: %w", method, url, err)
+		}
+		total := time.Since(start)
+		checked.total = total
+
+		metrics := timing.metrics(start)
+		metrics["protocol"] = resp.Proto
+		metrics["redirects"] = redirects
+		metrics["total_ms"] = models.Milliseconds(total)
+		if redirects > 0 {
+			metrics["final_url"] = resp.Request.URL.String()
+		}
+		result := &models.Result{
+			Summary:    fmt.Sprintf("http %s %s success, status: %d, time: %v", method, url, resp.StatusCode, elapsed),
+			LatencyMS:  models.Milliseconds(elapsed),
+			StatusCode: resp.StatusCode,
+			Bytes:      checked.size,
+			ResolvedIP: timing.resolvedIP(),
+			Metrics:    metrics,
+		}
+		if !opts.expected(resp.StatusCode) {
+			problem := "unexpected"
+			if len(opts.ExpectedStatus) == 0 {
+				problem = "error"
+			}
+			result.Summary = fmt.Sprintf("http %s %s returned %s status: %d, time: %v", method, url, problem, resp.StatusCode, elapsed)
+			return result, fmt.Errorf("http %s %s returned %s status: %d", method, url, problem, resp.StatusCode)
+		}
+		if len(opts.Assertions) == 0 {
+			return result, nil
+		}
+		var failed []string
+		result.Assertions, failed = checkAssertions(opts.Assertions, checked)
+		if len(failed) > 0 {
+			result.Summary = fmt.Sprintf("http %s %s failed %d of %d assertions, status: %d, time: %v", method, url, len(failed), len(opts.Assertions), resp.StatusCode, elapsed)
+			return result, fmt.Errorf("http %s %s failed %d of %d assertions: %s", method, url, len(failed), len(opts.Assertions), strings.Join(failed, "; "))
+		}
+		return result, nil
+	}
+}
+
+// buildRequest returns the request of a run with the method, body, headers and authentication of the options
+func (o HTTPOptions) buildRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, o.Method, url, strings.NewReader(o.Body))
+	if err != nil {
+		return nil, err
+	}
+	if o.Body == "" {
+		req.Body, req.ContentLength = http.NoBody, 0
+	}
+	for key, values := range o.Header {
+		if http.CanonicalHeaderKey(key) == "Host" {
+			req.Host = values[0]
+			continue
 		}
+		req.Header[http.CanonicalHeaderKey(key)] = values
+	}
+	switch {
+	case o.Username != "":
+		req.SetBasicAuth(o.Username, o.Password)
+	case o.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+o.BearerToken)
+	}
+	return req, nil
+}
 
-		return fmt.Sprintf("http get %s success, status: %d, time: %v", url, resp.StatusCode, elapsed), nil
+// newHTTPClient returns a client for one run that follows the redirect policy of the options and counts the redirects it follows
+//
+// The client does not keep connections alive, so that every run times its connects.
+func (o HTTPOptions) newHTTPClient(redirects *int) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(_ *http.Request, via []*http.Request) error {
+			if o.Redirects == RedirectNone {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > o.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", o.MaxRedirects)
+			}
+			*redirects = len(via)
+			return nil
+		},
 	}
 }
+
+// readsBody reports whether an assertion needs the response body in memory
+func (o HTTPOptions) readsBody() bool {
+	for _, a := range o.Assertions {
+		if a.readsBody {
+			return true
+		}
+	}
+	return false
+}
+
+// readBody reads a response body to the end, keeping up to constants.HTTPAssertBodyLimit bytes of it when keep is set
+func readBody(body io.Reader, r *httpResponse, keep bool) error {
+	if keep {
+		start, err := io.ReadAll(io.LimitReader(body, constants.HTTPAssertBodyLimit+1))
+		if err != nil {
+			return err
+		}
+		r.size = int64(len(start))
+		if r.truncated = len(start) > constants.HTTPAssertBodyLimit; r.truncated {
+			start = start[:constants.HTTPAssertBodyLimit]
+		}
+		r.body = start
+	}
+	n, err := io.Copy(io.Discard, body)
+	r.size += n
+	return err
+}
+
+// httpTiming collects the phases of an HTTP task run, the trace hooks may run on several goroutines
+type httpTiming struct {
+	lock      sync.Mutex
+	dnsStart  time.Time
+	connects  map[string]time.Time
+	tlsStart  time.Time
+	dns       time.Duration
+	connect   time.Duration
+	tls       time.Duration
+	firstByte time.Time
+	ip        string
+}
+
+// trace returns the hooks that fill the timing
+func (t *httpTiming) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.dns += time.Since(t.dnsStart)
+		},
+		ConnectStart: func(network, addr string) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.connects[network+" "+addr] = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			// only the connect that won counts, parallel attempts to other addresses fail or are dropped
+			if err == nil {
+				t.connect += time.Since(t.connects[network+" "+addr])
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.tls += time.Since(t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.ip = remoteIP(info.Conn.RemoteAddr())
+		},
+		GotFirstResponseByte: func() {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			t.firstByte = time.Now()
+		},
+	}
+}
+
+// metrics returns the phase timings of a run started at start in milliseconds
+func (t *httpTiming) metrics(start time.Time) map[string]any {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	metrics := map[string]any{
+		"dns_ms":     models.Milliseconds(t.dns),
+		"connect_ms": models.Milliseconds(t.connect),
+		"tls_ms":     models.Milliseconds(t.tls),
+	}
+	if !t.firstByte.IsZero() {
+		metrics["ttfb_ms"] = models.Milliseconds(t.firstByte.Sub(start))
+	}
+	return metrics
+}
+
+// resolvedIP returns the address of the last connection
+func (t *httpTiming) resolvedIP() string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.ip
+}
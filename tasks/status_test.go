@@ -1,26 +1,35 @@
 package tasks
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestMakeGetStatusTask_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("hello"))
 	}))
 	defer server.Close()
 
 	task := MakeGetStatusTask(server.URL)
-	result, err := task()
+	result, err := task(context.Background())
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	if result == "" {
+	if result == nil || result.Summary == "" {
 		t.Fatal("expected non-empty result")
 	}
+	if result.StatusCode != http.StatusOK || result.Bytes != 5 || result.ResolvedIP != "127.0.0.1" {
+		t.Errorf("unexpected structured result: %+v", result)
+	}
 }
 
 func TestMakeGetStatusTask_FailureStatus(t *testing.T) {
@@ -30,25 +39,164 @@ func TestMakeGetStatusTask_FailureStatus(t *testing.T) {
 	defer server.Close()
 
 	task := MakeGetStatusTask(server.URL)
-	result, err := task()
+	result, err := task(context.Background())
 
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
-	if result != "" {
-		t.Errorf("expected empty result, got %q", result)
+	if result == nil || result.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected a result with status %d, got %+v", http.StatusInternalServerError, result)
 	}
 }
 
 func TestMakeGetStatusTask_ConnectionError(t *testing.T) {
 	task := MakeGetStatusTask("http://invalid.localhost")
 
-	result, err := task()
+	result, err := task(context.Background())
 
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
-	if result != "" {
-		t.Errorf("expected empty result, got %q", result)
+	if result != nil {
+		t.Errorf("expected no result, got %+v", result)
+	}
+}
+
+func TestMakeGetStatusTask_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	task := MakeGetStatusTask(server.URL)
+	_, err := task(ctx)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestParseStatusRange(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    StatusRange
+		wantErr bool
+	}{
+		{"200", StatusRange{200, 200}, false},
+		{"2xx", StatusRange{200, 299}, false},
+		{"4XX", StatusRange{400, 499}, false},
+		{"200-204", StatusRange{200, 204}, false},
+		{"6xx", StatusRange{}, true},
+		{"204-200", StatusRange{}, true},
+		{"99", StatusRange{}, true},
+		{"200-600", StatusRange{}, true},
+		{"2x0", StatusRange{}, true},
+		{"", StatusRange{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStatusRange(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: expected error %v, got %v", tt.in, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("%q: expected %+v, got %+v", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestMakeHTTPTask_Request(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("X-Check") != "1" || string(body) != `{"ok":true}` || !ok || user != "user" || pass != "secret" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	task := MakeHTTPTask(server.URL, HTTPOptions{
+		Method:         http.MethodPost,
+		Header:         http.Header{"X-Check": {"1"}},
+		Body:           `{"ok":true}`,
+		Username:       "user",
+		Password:       "secret",
+		ExpectedStatus: []StatusRange{{201, 201}},
+	})
+	result, err := task(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.StatusCode != http.StatusCreated || !strings.HasPrefix(result.Summary, "http post ") {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	for _, key := range []string{"dns_ms", "connect_ms", "tls_ms", "ttfb_ms", "total_ms"} {
+		if _, ok := result.Metrics[key].(float64); !ok {
+			t.Errorf("expected metric %s, got %v", key, result.Metrics)
+		}
+	}
+	if result.Metrics["connect_ms"].(float64) <= 0 || result.Metrics["ttfb_ms"].(float64) > result.Metrics["total_ms"].(float64) {
+		t.Errorf("unexpected timing: %v", result.Metrics)
+	}
+}
+
+func TestMakeHTTPTask_BearerToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	if _, err := MakeHTTPTask(server.URL, HTTPOptions{BearerToken: "token"})(context.Background()); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if _, err := MakeHTTPTask(server.URL, HTTPOptions{})(context.Background()); err == nil {
+		t.Error("expected error without token, got nil")
+	}
+}
+
+func TestMakeHTTPTask_Redirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/b", http.StatusFound) })
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/c", http.StatusMovedPermanently) })
+	mux.HandleFunc("/c", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("done")) })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		opts    HTTPOptions
+		status  int
+		wantErr bool
+	}{
+		{"follow", HTTPOptions{}, http.StatusOK, false},
+		{"none", HTTPOptions{Redirects: RedirectNone, ExpectedStatus: []StatusRange{{300, 399}}}, http.StatusFound, false},
+		{"too many", HTTPOptions{MaxRedirects: 1}, 0, true},
+		{"unexpected", HTTPOptions{ExpectedStatus: []StatusRange{{300, 399}}}, 0, true},
+	}
+	for _, tt := range tests {
+		result, err := MakeHTTPTask(server.URL+"/a", tt.opts)(context.Background())
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %+v", tt.name, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.name, err)
+		}
+		if result.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, result.StatusCode)
+		}
+		if tt.name == "follow" && (result.Metrics["redirects"] != 2 || result.Metrics["final_url"] != server.URL+"/c") {
+			t.Errorf("%s: unexpected metrics %v", tt.name, result.Metrics)
+		}
 	}
 }
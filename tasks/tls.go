@@ -0,0 +1,141 @@
+package tasks
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+)
+
+// TLSOptions configures a TLS certificate task, zero fields take the defaults
+type TLSOptions struct {
+	// Port is the TCP port of the TLS server, constants.TLSPort when zero
+	Port int
+	// ServerName is sent as SNI and verified against the certificate, the address when empty
+	ServerName string
+	// MinDaysValid fails the task when the leaf certificate expires in fewer days, constants.TLSMinDaysValid when nil and no threshold when zero
+	MinDaysValid *int
+	// AllowInvalidChain reports a chain that does not verify without failing the task
+	AllowInvalidChain bool
+	// RootCAs verifies the chain, the system roots when nil
+	RootCAs *x509.CertPool
+}
+
+// withDefaults fills the zero fields of the options, and MinDaysValid when nil
+func (o TLSOptions) withDefaults(address string) TLSOptions {
+	if o.Port == 0 {
+		o.Port = constants.TLSPort
+	}
+	if o.ServerName == "" {
+		o.ServerName = address
+	}
+	if o.MinDaysValid == nil {
+		days := constants.TLSMinDaysValid
+		o.MinDaysValid = &days
+	}
+	return o
+}
+
+// MakeTLSCertTask returns a task that performs a TLS handshake with the given address and checks its certificate
+//
+// The chain is verified apart from the handshake, so that the certificate of a
+// server with an invalid chain is still reported. The task fails when the leaf
+// certificate has expired or expires in less than MinDaysValid days, and when
+// the chain does not verify unless AllowInvalidChain is set. These failures
+// keep the result.
+func MakeTLSCertTask(address string, opts TLSOptions) func(ctx context.Context) (*models.Result, error) {
+	opts = opts.withDefaults(address)
+	target := net.JoinHostPort(address, strconv.Itoa(opts.Port))
+	minDays := *opts.MinDaysValid
+	return func(ctx context.Context) (*models.Result, error) {
+		start := time.Now()
+		var dialer net.Dialer
+		raw, err := dialer.DialContext(ctx, "tcp", target)
+		if err != nil {
+			return nil, fmt.Errorf("tls %s failed: %w", target, err)
+		}
+		// #nosec G402 -- the chain is verified below so that an invalid one can be reported
+		conn := tls.Client(raw, &tls.Config{ServerName: opts.ServerName, InsecureSkipVerify: true})
+		defer func() { _ = conn.Close() }()
+		if err := conn.HandshakeContext(ctx); err != nil {
+			return nil, fmt.Errorf("tls %s failed: %w", target, err)
+		}
+		elapsed := time.Since(start)
+
+		state := conn.ConnectionState()
+		if len(state.PeerCertificates) == 0 {
+			return nil, fmt.Errorf("tls %s failed: no certificate", target)
+		}
+		leaf := state.PeerCertificates[0]
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, chainErr := leaf.Verify(x509.VerifyOptions{
+			DNSName:       opts.ServerName,
+			Intermediates: intermediates,
+			Roots:         opts.RootCAs,
+		})
+		// whole days, counted toward zero on both sides of the expiry
+		untilExpiry := time.Until(leaf.NotAfter)
+		daysLeft := int(untilExpiry.Hours() / 24)
+		protocol := tls.VersionName(state.Version)
+		cipher := tls.CipherSuiteName(state.CipherSuite)
+
+		metrics := map[string]any{
+			"server_name":       opts.ServerName,
+			"subject":           leaf.Subject.String(),
+			"sans":              subjectAltNames(leaf),
+			"issuer":            leaf.Issuer.String(),
+			"not_before":        leaf.NotBefore.UTC().Format(time.RFC3339),
+			"not_after":         leaf.NotAfter.UTC().Format(time.RFC3339),
+			"days_until_expiry": daysLeft,
+			"chain_valid":       chainErr == nil,
+			"chain_length":      len(state.PeerCertificates),
+			"protocol":          protocol,
+			"cipher":            cipher,
+		}
+		if chainErr != nil {
+			metrics["chain_error"] = chainErr.Error()
+		}
+		result := &models.Result{
+			Summary: fmt.Sprintf("tls %s success, expires in %d days (%s), issuer %s, %s %s",
+				target, daysLeft, leaf.NotAfter.UTC().Format(time.DateOnly), leaf.Issuer.CommonName, protocol, cipher),
+			LatencyMS:  models.Milliseconds(elapsed),
+			ResolvedIP: remoteIP(raw.RemoteAddr()),
+			Metrics:    metrics,
+		}
+		switch {
+		case untilExpiry <= 0:
+			result.Summary = fmt.Sprintf("tls %s certificate expired %d days ago (%s)", target, -daysLeft, leaf.NotAfter.UTC().Format(time.DateOnly))
+			return result, fmt.Errorf("tls %s certificate expired %d days ago", target, -daysLeft)
+		case chainErr != nil && !opts.AllowInvalidChain:
+			result.Summary = fmt.Sprintf("tls %s invalid chain: %v", target, chainErr)
+			return result, fmt.Errorf("tls %s invalid chain: %w", target, chainErr)
+		case daysLeft < minDays:
+			result.Summary = fmt.Sprintf("tls %s certificate expires in %d days (%s), under the %d days threshold",
+				target, daysLeft, leaf.NotAfter.UTC().Format(time.DateOnly), minDays)
+			return result, fmt.Errorf("tls %s certificate expires in %d days, under the %d days threshold", target, daysLeft, minDays)
+		}
+		return result, nil
+	}
+}
+
+// subjectAltNames returns the DNS names, IP addresses, emails and URIs a certificate is valid for
+func subjectAltNames(cert *x509.Certificate) []string {
+	names := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	names = append(names, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return names
+}
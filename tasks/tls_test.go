@@ -0,0 +1,177 @@
+package tasks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tlsTarget returns the host and port of a test server
+func tlsTarget(t *testing.T, server *httptest.Server) (string, int) {
+	t.Helper()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := strconv.Atoi(port)
+	return host, n
+}
+
+// certServer starts a TLS server with a self-signed certificate for 127.0.0.1 that expires at notAfter
+func certServer(t *testing.T, notAfter time.Time) (string, int) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "cert.test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().AddDate(0, 0, -30),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return tlsTarget(t, server)
+}
+
+// days returns a pointer to a number of days
+func days(n int) *int {
+	return &n
+}
+
+// serverRoots returns a pool trusting the certificate of a test server
+func serverRoots(server *httptest.Server) *x509.CertPool {
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	return roots
+}
+
+func TestMakeTLSCertTask(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	host, port := tlsTarget(t, server)
+
+	result, err := MakeTLSCertTask(host, TLSOptions{Port: port, RootCAs: serverRoots(server)})(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	m := result.Metrics
+	if m["chain_valid"] != true || m["protocol"] != "TLS 1.3" || m["cipher"] == "" || m["server_name"] != host {
+		t.Errorf("unexpected metrics: %v", m)
+	}
+	if days, _ := m["days_until_expiry"].(int); days < 365 {
+		t.Errorf("expected the test certificate to be valid for years, got %v days", m["days_until_expiry"])
+	}
+	if sans, _ := m["sans"].([]string); !slices.Contains(sans, "127.0.0.1") || !slices.Contains(sans, "example.com") {
+		t.Errorf("unexpected sans: %v", m["sans"])
+	}
+	if !strings.Contains(m["subject"].(string), "Acme Co") || !strings.HasPrefix(result.Summary, "tls "+server.Listener.Addr().String()+" success") {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if result.ResolvedIP != "127.0.0.1" {
+		t.Errorf("expected resolved IP 127.0.0.1, got %q", result.ResolvedIP)
+	}
+}
+
+func TestMakeTLSCertTask_Failures(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	host, port := tlsTarget(t, server)
+	roots := serverRoots(server)
+
+	tests := []struct {
+		name    string
+		opts    TLSOptions
+		wantErr string
+	}{
+		{"threshold", TLSOptions{Port: port, RootCAs: roots, MinDaysValid: days(1 << 20)}, "under the 1048576 days threshold"},
+		{"untrusted", TLSOptions{Port: port}, "invalid chain"},
+		{"untrusted allowed", TLSOptions{Port: port, AllowInvalidChain: true}, ""},
+		{"server name", TLSOptions{Port: port, RootCAs: roots, ServerName: "other.test"}, "invalid chain"},
+		{"sni", TLSOptions{Port: port, RootCAs: roots, ServerName: "example.com"}, ""},
+	}
+	for _, tt := range tests {
+		result, err := MakeTLSCertTask(host, tt.opts)(context.Background())
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: expected no error, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+		if result == nil || result.Metrics["subject"] == nil {
+			t.Errorf("%s: expected the certificate in the result, got %+v", tt.name, result)
+		}
+	}
+}
+
+func TestMakeTLSCertTask_Expired(t *testing.T) {
+	host, port := certServer(t, time.Now().AddDate(0, 0, -3))
+
+	result, err := MakeTLSCertTask(host, TLSOptions{Port: port, AllowInvalidChain: true})(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "certificate expired 3 days ago") {
+		t.Fatalf("expected an expired certificate, got %v", err)
+	}
+	if result == nil || result.Metrics["days_until_expiry"] != -3 || result.Metrics["chain_valid"] != false {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestMakeTLSCertTask_MinDaysValid(t *testing.T) {
+	host, port := certServer(t, time.Now().Add(5*24*time.Hour+time.Hour))
+
+	tests := []struct {
+		name    string
+		minDays *int
+		wantErr bool
+	}{
+		{"default", nil, true},
+		{"off", days(0), false},
+		{"under", days(3), false},
+		{"over", days(6), true},
+	}
+	for _, tt := range tests {
+		opts := TLSOptions{Port: port, MinDaysValid: tt.minDays, AllowInvalidChain: true}
+		_, err := MakeTLSCertTask(host, opts)(context.Background())
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestMakeTLSCertTask_ConnectionError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	result, err := MakeTLSCertTask("127.0.0.1", TLSOptions{Port: port})(context.Background())
+	if err == nil || result != nil {
+		t.Errorf("expected a connection error without result, got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/scheduler"
+)
+
+// Payload is the JSON body of a webhook
+type Payload struct {
+	Event string      `json:"event"`
+	Task  TaskPayload `json:"task"`
+}
+
+// TaskPayload is the finished task sent in a webhook
+type TaskPayload struct {
+	ID            string               `json:"id"`
+	Type          constants.TaskType   `json:"type"`
+	Status        constants.TaskStatus `json:"status"`
+	Attempts      int                  `json:"attempts"`
+	CreatedAt     time.Time            `json:"created_at"`
+	FinishedAt    time.Time            `json:"finished_at"`
+	QueueWaitMS   int64                `json:"queue_wait_ms"`
+	RunDurationMS int64                `json:"run_duration_ms"`
+	ScheduleID    string               `json:"schedule_id,omitempty"`
+	BatchID       string               `json:"batch_id,omitempty"`
+	Tags          []string             `json:"tags,omitempty"`
+	AttemptErrors []string             `json:"attempt_errors,omitempty"`
+	Result        *models.Result       `json:"result,omitempty"`
+	Error         string               `json:"error,omitempty"`
+}
+
+// newPayload returns the body sent for a final event
+func newPayload(event scheduler.Event) Payload {
+	task := event.Task
+	payload := Payload{
+		Event: string(event.Type),
+		Task: TaskPayload{
+			ID:            task.ID,
+			Type:          task.Type,
+			Status:        task.Status,
+			Attempts:      task.Attempts,
+			CreatedAt:     task.CreatedAt,
+			FinishedAt:    task.FinishedAt,
+			QueueWaitMS:   task.QueueWait.Milliseconds(),
+			RunDurationMS: task.RunDuration.Milliseconds(),
+			ScheduleID:    task.ScheduleID,
+			BatchID:       task.BatchID,
+			Tags:          task.Tags,
+			AttemptErrors: task.AttemptErrors,
+			Result:        task.Result,
+		},
+	}
+	if task.Err != nil {
+		payload.Task.Error = task.Err.Error()
+	}
+	return payload
+}
@@ -0,0 +1,230 @@
+// Package webhook sends the outcome of finished tasks to callback URLs
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/artnikel/taskscheduler/constants"
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/scheduler"
+)
+
+// Request headers of a webhook
+const (
+	// HeaderEvent names the task event, succeeded or failed
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderDelivery is the task ID, it is the same for every attempt so receivers can drop duplicates
+	HeaderDelivery = "X-Webhook-Delivery"
+	// HeaderAttempt counts the attempts of the delivery from 1
+	HeaderAttempt = "X-Webhook-Attempt"
+	// HeaderTimestamp is the Unix time the attempt was sent, it is part of the signature
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is the HMAC of the request, see Sign
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Config holds the settings of a Dispatcher
+type Config struct {
+	// URL receives the outcome of tasks created without a callback URL, no webhook is sent for them when empty
+	URL string
+	// Secret signs every request, requests are not signed when empty
+	Secret string
+	// Timeout limits one request, constants.WebhookTimeout is used when zero
+	Timeout time.Duration
+	// Retry spaces the attempts of a delivery, the constants.Webhook defaults are used when MaxAttempts is zero
+	Retry scheduler.RetryPolicy
+}
+
+// Dispatcher posts a JSON payload to the callback URL of every task that ends done or failed
+//
+// Deliveries that do not get a 2xx response are retried with backoff, except
+// for client errors other than 408 and 429. Every attempt is logged on the task
+// with Scheduler.RecordDelivery. Deliveries still pending when the dispatcher
+// is closed are dropped.
+type Dispatcher struct {
+	sched  *scheduler.Scheduler
+	cfg    Config
+	client *http.Client
+	sub    *scheduler.Subscription
+	slots  chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher starts sending webhooks for the tasks of the scheduler that finish from now on
+func NewDispatcher(s *scheduler.Scheduler, cfg Config) *Dispatcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = constants.WebhookTimeout
+	}
+	if cfg.Retry.MaxAttempts <= 0 {
+		cfg.Retry = scheduler.RetryPolicy{
+			MaxAttempts:    constants.WebhookMaxAttempts,
+			InitialBackoff: constants.WebhookInitialBackoff,
+			Multiplier:     2,
+			MaxBackoff:     constants.WebhookMaxBackoff,
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		sched:  s,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		slots:  make(chan struct{}, constants.WebhookConcurrency),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	d.sub, _ = s.Events().Subscribe(math.MaxUint64)
+	d.wg.Add(1)
+	go d.run()
+	return d
+}
+
+// Close stops sending webhooks and waits for the requests in flight
+func (d *Dispatcher) Close() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+// run starts a delivery for every final event until the dispatcher or the event bus is closed
+func (d *Dispatcher) run() {
+	defer d.wg.Done()
+	bus := d.sched.Events()
+	defer func() { d.sub.Close() }()
+	var lastID uint64
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case event, ok := <-d.sub.C:
+			if ok {
+				lastID = event.ID
+				d.handle(event)
+				continue
+			}
+			select {
+			case <-bus.Done():
+				return
+			default:
+			}
+			// dropped for falling behind, the replay buffer holds the events missed meanwhile
+			var missed []scheduler.Event
+			d.sub, missed = bus.Subscribe(lastID)
+			for _, event := range missed {
+				lastID = event.ID
+				d.handle(event)
+			}
+		}
+	}
+}
+
+// handle starts the delivery of a final event to the callback URL of its task
+func (d *Dispatcher) handle(event scheduler.Event) {
+	if event.Type != scheduler.EventSucceeded && event.Type != scheduler.EventFailed {
+		return
+	}
+	url := event.Task.CallbackURL
+	if url == "" {
+		url = d.cfg.URL
+	}
+	if url == "" {
+		return
+	}
+	body, err := json.Marshal(newPayload(event))
+	if err != nil {
+		return
+	}
+	d.wg.Add(1)
+	go d.deliver(event.Task.ID, string(event.Type), url, body)
+}
+
+// deliver sends a webhook until it is accepted, fails for good or runs out of attempts
+func (d *Dispatcher) deliver(taskID, event, url string, body []byte) {
+	defer d.wg.Done()
+	for attempt := 1; ; attempt++ {
+		select {
+		case d.slots <- struct{}{}:
+		case <-d.ctx.Done():
+			return
+		}
+		delivery, retry := d.send(taskID, event, url, body, attempt)
+		<-d.slots
+		if d.ctx.Err() != nil {
+			// interrupted by Close, the attempt says nothing about the receiver
+			return
+		}
+		_ = d.sched.RecordDelivery(taskID, delivery)
+		if !retry || attempt >= d.cfg.Retry.MaxAttempts {
+			return
+		}
+		timer := time.NewTimer(d.cfg.Retry.Backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-d.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// send makes one attempt and reports whether a failed attempt is worth retrying
+func (d *Dispatcher) send(taskID, event, url string, body []byte, attempt int) (models.Delivery, bool) {
+	delivery := models.Delivery{Attempt: attempt, Event: event, URL: url, SentAt: time.Now()}
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, false
+	}
+	timestamp := strconv.FormatInt(delivery.SentAt.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, taskID)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(attempt))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if d.cfg.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(d.cfg.Secret, timestamp, body))
+	}
+	resp, err := d.client.Do(req)
+	delivery.DurationMS = models.Milliseconds(time.Since(delivery.SentAt))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, true
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return delivery, false
+	}
+	delivery.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return delivery, true
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return delivery, false
+	default:
+		return delivery, true
+	}
+}
+
+// Sign returns the signature header value of a request body sent at the given timestamp
+//
+// It is "sha256=" followed by the hex HMAC-SHA256 of the timestamp, a dot and the body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
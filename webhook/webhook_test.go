@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artnikel/taskscheduler/models"
+	"github.com/artnikel/taskscheduler/scheduler"
+)
+
+// waitDeliveries polls the delivery log of a task until it has n attempts
+func waitDeliveries(t *testing.T, s *scheduler.Scheduler, id string, n int) []models.Delivery {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if task, ok := s.GetTask(id); ok && len(task.Deliveries) >= n {
+			return task.Deliveries
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	task, _ := s.GetTask(id)
+	t.Fatalf("expected %d deliveries, got %+v", n, task.Deliveries)
+	return nil
+}
+
+func succeed(context.Context) (*models.Result, error) {
+	return &models.Result{Summary: "ok"}, nil
+}
+
+func TestDispatcher_Signed(t *testing.T) {
+	received := make(chan Payload, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get(HeaderSignature), Sign("secret", r.Header.Get(HeaderTimestamp), body); got != want {
+			t.Errorf("expected signature %s, got %s", want, got)
+		}
+		if r.Header.Get(HeaderEvent) != "succeeded" || r.Header.Get(HeaderAttempt) != "1" {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+		var payload Payload
+		_ = json.Unmarshal(body, &payload)
+		received <- payload
+	}))
+	defer receiver.Close()
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	d := NewDispatcher(s, Config{Secret: "secret"})
+	defer d.Close()
+
+	id, _ := s.AddTask(succeed, scheduler.TaskOptions{CallbackURL: receiver.URL})
+	deliveries := waitDeliveries(t, s, id, 1)
+	if deliveries[0].StatusCode != http.StatusOK || deliveries[0].Error != "" || deliveries[0].URL != receiver.URL {
+		t.Errorf("unexpected delivery: %+v", deliveries[0])
+	}
+	payload := <-received
+	if payload.Event != "succeeded" || payload.Task.ID != id || payload.Task.Result == nil || payload.Task.Result.Summary != "ok" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestDispatcher_Retry(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes []int
+		want  []int
+	}{
+		{"server error", []int{http.StatusBadGateway, http.StatusOK}, []int{http.StatusBadGateway, http.StatusOK}},
+		{"too many requests", []int{http.StatusTooManyRequests, http.StatusNoContent}, []int{http.StatusTooManyRequests, http.StatusNoContent}},
+		{"client error", []int{http.StatusBadRequest, http.StatusOK}, []int{http.StatusBadRequest}},
+		{"out of attempts", []int{500, 500, 500, 200}, []int{500, 500, 500}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.codes[min(int(calls.Add(1))-1, len(tt.codes)-1)])
+			}))
+			defer receiver.Close()
+			s := scheduler.NewScheduler(1)
+			defer s.Stop()
+			d := NewDispatcher(s, Config{URL: receiver.URL, Retry: scheduler.RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond}})
+			defer d.Close()
+
+			id, _ := s.AddTask(func(context.Context) (*models.Result, error) {
+				return nil, errors.New("boom")
+			}, scheduler.TaskOptions{Retry: &scheduler.RetryPolicy{MaxAttempts: 1}})
+			deliveries := waitDeliveries(t, s, id, len(tt.want))
+			time.Sleep(50 * time.Millisecond)
+			if task, _ := s.GetTask(id); len(task.Deliveries) != len(tt.want) {
+				t.Fatalf("expected %d deliveries, got %d", len(tt.want), len(task.Deliveries))
+			}
+			for i, delivery := range deliveries {
+				if delivery.Attempt != i+1 || delivery.StatusCode != tt.want[i] || delivery.Event != "failed" {
+					t.Errorf("unexpected delivery %d: %+v", i, delivery)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatcher_Skipped(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		calls.Add(1)
+	}))
+	defer receiver.Close()
+	s := scheduler.NewScheduler(1)
+	defer s.Stop()
+	d := NewDispatcher(s, Config{})
+	defer d.Close()
+
+	// no callback URL anywhere
+	_, _ = s.AddTask(succeed, scheduler.TaskOptions{})
+	// cancelled tasks do not call back
+	cancelled, _ := s.AddTask(succeed, scheduler.TaskOptions{CallbackURL: receiver.URL, RunAt: time.Now().Add(time.Hour)})
+	_ = s.Cancel(cancelled)
+	time.Sleep(50 * time.Millisecond)
+	if calls.Load() != 0 {
+		t.Errorf("expected no webhook, got %d", calls.Load())
+	}
+}